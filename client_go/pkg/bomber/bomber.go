@@ -7,9 +7,14 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/N3moAhead/bombahead/client_go/pkg/wire"
 	"github.com/gorilla/websocket"
 )
 
@@ -17,12 +22,27 @@ type BomberBot interface {
 	CalcNextMove(bomberId string, state ClassicStatePayload) PlayerMove
 }
 
+// BombingBot can be implemented in addition to BomberBot to place
+// a bomb and move in the same tick. The bomb is placed before the move.
+type BombingBot interface {
+	BomberBot
+	CalcNextInput(bomberId string, state ClassicStatePayload) (move PlayerMove, bomb bool)
+}
+
+// RESUME_ATTEMPTS is how often the bot tries to reconnect, once per second,
+// when the connection drops during a game
+const RESUME_ATTEMPTS = 10
+
 type Bomber struct {
 	bomberID    string
+	authToken   string
+	mu          sync.Mutex // Guards the fields below, the main loop and the pumps share them
+	resumeToken string     // Set while a game is running
 	conn        *websocket.Conn
-	done        chan struct{}
+	done        chan struct{} // Closed when the read pump of conn stops
 	interrupt   chan os.Signal
-	sendChannel chan Message
+	sendChannel chan frame
+	binary      atomic.Bool // Whether MessagePack was negotiated
 	bot         BomberBot
 }
 
@@ -30,43 +50,44 @@ func NewBomber(bot BomberBot) *Bomber {
 	return &Bomber{
 		done:        make(chan struct{}),
 		interrupt:   make(chan os.Signal, 1),
-		sendChannel: make(chan Message),
+		sendChannel: make(chan frame),
 		bot:         bot,
 	}
 }
 
+// frame is an encoded message waiting to be written to the websocket
+type frame struct {
+	binary bool
+	data   []byte
+}
+
 func (b *Bomber) send(msgType MessageType, payload any) {
-	payloadBytes, err := json.Marshal(payload)
+	binary := b.binary.Load()
+	data, err := wire.Encode(binary, string(msgType), payload)
 	if err != nil {
-		error("Error marshalling payload for send: %v", err)
+		error("Error marshalling message for send: %v", err)
 		return
 	}
 
-	msg := Message{
-		Type:    msgType,
-		Payload: payloadBytes,
-	}
-
-	b.sendChannel <- msg
+	b.sendChannel <- frame{binary: binary, data: data}
 }
 
-func (b *Bomber) writePump() {
+func (b *Bomber) writePump(conn *websocket.Conn, done chan struct{}) {
 	for {
 		select {
-		case message := <-b.sendChannel:
-			messageBytes, err := json.Marshal(message)
-			if err != nil {
-				error("Error marshalling message for write: %v", err)
-				continue
+		case f := <-b.sendChannel:
+			frameType := websocket.TextMessage
+			if f.binary {
+				frameType = websocket.BinaryMessage
 			}
-			if err := b.conn.WriteMessage(websocket.TextMessage, messageBytes); err != nil {
+			if err := conn.WriteMessage(frameType, f.data); err != nil {
 				error("Write Error: %v", err)
 				return
 			}
 		case <-b.interrupt:
 			// Main loop will handle the clean close message
 			return
-		case <-b.done:
+		case <-done:
 			// Read pump closed, so we should also close
 			return
 		}
@@ -76,50 +97,125 @@ func (b *Bomber) writePump() {
 func (b *Bomber) Start(u url.URL) {
 	info("Trying to connect to %s...", u.String())
 
-	authToken := os.Getenv("BOMBERMAN_CLIENT_AUTH_TOKEN")
+	b.authToken = os.Getenv("BOMBERMAN_CLIENT_AUTH_TOKEN")
 
 	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
 	if err != nil {
 		error("Error while trying to connect")
 		log.Fatal(err)
 	}
-	b.conn = conn
-
-	go b.writePump()
-	go b.ReadMessages()
+	done := b.startPumps(conn)
+
+	// Tell the server which protocol we speak before anything else
+	b.send(Hello, HelloPayload{ProtocolVersion: PROTOCOL_VERSION, Capabilities: Capabilities})
+
+	// Bots can play in a private room, "new" opens one and prints its join code
+	switch roomCode := os.Getenv("BOMBERMAN_ROOM_CODE"); roomCode {
+	case "":
+	case "new":
+		b.send(CreateRoom, CreateRoomPayload{})
+	default:
+		b.send(JoinRoom, JoinRoomPayload{Code: roomCode})
+	}
 
 	// Let's notify the server that we are ready for a game
 	payload := PlayerStatusUpdatePayload{
 		IsReady:   true,
-		AuthToken: authToken,
+		AuthToken: b.authToken,
 	}
 	b.send(PlayerStatusUpdate, payload)
 
 	signal.Notify(b.interrupt, os.Interrupt)
-	select {
-	case <-b.interrupt:
-		info("Detected interrupt closing connection")
-		err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	for {
+		select {
+		case <-b.interrupt:
+			info("Detected interrupt closing connection")
+			b.mu.Lock()
+			err := b.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			b.mu.Unlock()
+			if err != nil {
+				error("Error while closing connection: %v", err)
+			}
+			return
+		case <-done:
+			if b.getResumeToken() == "" {
+				info("The server closed the connection")
+				return
+			}
+			if done = b.resume(u); done == nil {
+				error("Could not reconnect to the running game")
+				return
+			}
+		}
+	}
+}
+
+// startPumps reads and writes the messages of a new connection.
+// The returned channel is closed when the connection is gone.
+func (b *Bomber) startPumps(conn *websocket.Conn) chan struct{} {
+	done := make(chan struct{})
+	b.mu.Lock()
+	b.conn = conn
+	b.done = done
+	b.mu.Unlock()
+	go b.writePump(conn, done)
+	go b.ReadMessages()
+	return done
+}
+
+func (b *Bomber) setResumeToken(token string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resumeToken = token
+}
+
+func (b *Bomber) getResumeToken() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.resumeToken
+}
+
+// resume reconnects after the connection dropped during a game and asks the
+// server to give us back our place in the game. It returns the done channel
+// of the new connection or nil if no connection could be established.
+func (b *Bomber) resume(u url.URL) chan struct{} {
+	for attempt := 1; attempt <= RESUME_ATTEMPTS; attempt++ {
+		info("Lost the connection during a game, reconnecting (%d/%d)...", attempt, RESUME_ATTEMPTS)
+		time.Sleep(time.Second)
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
 		if err != nil {
-			error("Error while closing connection: %v", err)
+			continue
 		}
-	case <-b.done:
-		info("The server closed the connection")
+		b.binary.Store(false)
+		done := b.startPumps(conn)
+		b.send(Hello, HelloPayload{ProtocolVersion: PROTOCOL_VERSION, Capabilities: Capabilities})
+		b.send(Resume, ResumePayload{ResumeToken: b.getResumeToken()})
+		// If the game can't be resumed anymore we are at least ready for the next one
+		b.send(PlayerStatusUpdate, PlayerStatusUpdatePayload{IsReady: true, AuthToken: b.authToken})
+		return done
 	}
+	return nil
 }
 
 func (b *Bomber) ReadMessages() {
-	defer close(b.done)
+	b.mu.Lock()
+	conn, done := b.conn, b.done
+	b.mu.Unlock()
+	defer close(done)
+
+	// The last state of this connection, needed to apply the next delta
+	var state *ClassicStatePayload
 	for {
-		_, messageBytes, err := b.conn.ReadMessage()
+		frameType, messageBytes, err := conn.ReadMessage()
 		if err != nil {
 			error("Read Error %v", err)
 			return
 		}
-		var msg Message
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
+		msgType, payload, err := wire.Decode(frameType == websocket.BinaryMessage, messageBytes)
+		if err != nil {
 			continue
 		}
+		msg := Message{Type: MessageType(msgType), Payload: payload}
 		switch msg.Type {
 		case Welcome:
 			var payload WelcomeMessage
@@ -127,12 +223,46 @@ func (b *Bomber) ReadMessages() {
 				error("Error unmarshalling WelcomeMessage: %v", err)
 				continue
 			}
+			// Every welcome carries the protocol the connection speaks right now,
+			// the one sent on connect stays at the base protocol until our hello is answered
+			b.binary.Store(slices.Contains(payload.Capabilities, "msgpack"))
+			if b.bomberID != "" {
+				if payload.ProtocolVersion > 1 {
+					info("Negotiated protocol version %d with capabilities %v", payload.ProtocolVersion, payload.Capabilities)
+				}
+				continue
+			}
 			b.bomberID = payload.ClientID
 			success("You connected to the bomberman server: %s", b.bomberID)
 			info("Available Games:")
 			for _, gameInfo := range payload.CurrentGames {
 				info("- %s: %s", gameInfo.Name, gameInfo.Description)
 			}
+		case RoomUpdate:
+			var payload RoomUpdatePayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				error("Error unmarshalling RoomUpdatePayload: %v", err)
+				continue
+			}
+			info("Room %s (%s, %d/%d players):", payload.Code, payload.GameMode, len(payload.Players), payload.MaxPlayers)
+			for pID, playerInfo := range payload.Players {
+				isReady := red("NOT READY")
+				if playerInfo.IsReady {
+					isReady = green("READY")
+				}
+				if pID == payload.Host {
+					info("- %s (host): %s", pID, isReady)
+				} else {
+					info("- %s: %s", pID, isReady)
+				}
+			}
+		case Notice:
+			var payload NoticePayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				error("Error unmarshalling NoticePayload: %v", err)
+				continue
+			}
+			info("Notice from the server: %s", payload.Message)
 		case UpdateLobby:
 			var payload LobbyUpdateMessage
 			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
@@ -166,27 +296,52 @@ func (b *Bomber) ReadMessages() {
 				error("Error while trying to unmarshal ErrorMessage: %v", err)
 			}
 			error("Server Error: %s", errorPaylaod.Message)
+			if errorPaylaod.CloseReason != "" {
+				error("The server is closing the connection: %s", errorPaylaod.CloseReason)
+			}
 		case GameStart:
 			var gameStartPayload GameStartPayload
 			err := json.Unmarshal(msg.Payload, &gameStartPayload)
 			if err != nil {
 				error("Error while trying to unmarshal GameStart message: %v", err)
 			}
+			b.setResumeToken(gameStartPayload.ResumeToken)
 			info("A new %s has started", gameStartPayload.Name)
+			info(
+				"Field: %dx%d, Fuse: %d ticks, Tick rate: %dms",
+				gameStartPayload.Config.FieldWidth,
+				gameStartPayload.Config.FieldHeight,
+				gameStartPayload.Config.FuseTicks,
+				gameStartPayload.Config.TickRateMs,
+			)
+			if team, ok := gameStartPayload.Teams[b.bomberID]; ok {
+				info("You are playing in team %d", team)
+			}
 		case ClassicState:
 			var classicState ClassicStatePayload
 			err := json.Unmarshal(msg.Payload, &classicState)
 			if err != nil {
 				error("Error while trying to unmarshal ClassicStatePayload: %v", err)
 			}
-			nextMove := b.bot.CalcNextMove(b.bomberID, classicState)
-			newPayload := ClassicInputPayload{
-				Move: nextMove,
+			state = &classicState
+			b.handleState(classicState)
+		case ClassicStateDelta:
+			var delta ClassicStateDeltaPayload
+			if err := json.Unmarshal(msg.Payload, &delta); err != nil {
+				error("Error while trying to unmarshal ClassicStateDeltaPayload: %v", err)
+				continue
+			}
+			// A state got lost, the next keyframe will bring us back in sync
+			if state == nil || state.Tick != delta.BaseTick {
+				info("Skipping the delta for tick %d, waiting for the next keyframe", delta.Tick)
+				continue
 			}
-			b.send(ClassicInput, newPayload)
-
-			printClassicState(classicState, b.bomberID)
+			classicState := applyDelta(*state, delta)
+			state = &classicState
+			b.handleState(classicState)
 		case BackToLobby:
+			state = nil
+			b.setResumeToken("")
 			info("Your back inside the lobby")
 			payload := PlayerStatusUpdatePayload{
 				IsReady: true,
@@ -198,6 +353,19 @@ func (b *Bomber) ReadMessages() {
 	}
 }
 
+// handleState lets the bot answer the state
+func (b *Bomber) handleState(classicState ClassicStatePayload) {
+	newPayload := ClassicInputPayload{Tick: classicState.Tick}
+	if bot, ok := b.bot.(BombingBot); ok {
+		newPayload.Move, newPayload.Bomb = bot.CalcNextInput(b.bomberID, classicState)
+	} else {
+		newPayload.Move = b.bot.CalcNextMove(b.bomberID, classicState)
+	}
+	b.send(ClassicInput, newPayload)
+
+	printClassicState(classicState, b.bomberID)
+}
+
 func printClassicState(s ClassicStatePayload, ownID string) {
 	width := s.Field.Width
 	height := s.Field.Height
@@ -239,6 +407,8 @@ func printClassicState(s ClassicStatePayload, ownID string) {
 					grid[y][x] = "🧱"
 				case BOX:
 					grid[y][x] = "📦"
+				case FOG:
+					grid[y][x] = "🌫️"
 				default:
 					grid[y][x] = "  "
 				}
@@ -246,6 +416,23 @@ func printClassicState(s ClassicStatePayload, ownID string) {
 		}
 	}
 
+	for _, powerUp := range s.PowerUps {
+		if powerUp.Pos.Y >= 0 && powerUp.Pos.Y < height && powerUp.Pos.X >= 0 && powerUp.Pos.X < width {
+			switch powerUp.Type {
+			case EXTRA_BOMB:
+				grid[powerUp.Pos.Y][powerUp.Pos.X] = "➕"
+			case BLAST_RADIUS:
+				grid[powerUp.Pos.Y][powerUp.Pos.X] = "🔥"
+			case BOMB_PASS:
+				grid[powerUp.Pos.Y][powerUp.Pos.X] = "👟"
+			case BOMB_KICK:
+				grid[powerUp.Pos.Y][powerUp.Pos.X] = "🦶"
+			case SHIELD:
+				grid[powerUp.Pos.Y][powerUp.Pos.X] = "💠"
+			}
+		}
+	}
+
 	for _, exp := range s.Explosions {
 		if exp.Y >= 0 && exp.Y < height && exp.X >= 0 && exp.X < width {
 			grid[exp.Y][exp.X] = "💥"
@@ -282,9 +469,29 @@ func printClassicState(s ClassicStatePayload, ownID string) {
 	sb.WriteString(strings.Repeat("══", width))
 	sb.WriteString("╝\n")
 
+	if s.SuddenDeath {
+		sb.WriteString("--- SUDDEN DEATH ---\n")
+		if s.NextWall != nil {
+			fmt.Fprintf(&sb, "Next wall at (%d,%d)\n", s.NextWall.X, s.NextWall.Y)
+		}
+	}
+
 	sb.WriteString("--- PLAYERS ---\n")
 	for _, p := range s.Players {
-		fmt.Fprintf(&sb, "%s Player ...%s | Health: %d, Score: %d\n", playerIcons[p.ID], p.ID[len(p.ID)-4:], p.Health, p.Score)
+		fmt.Fprintf(
+			&sb,
+			"%s Player ...%s | Team: %d, Health: %d, Score: %d, Bombs: %d, Radius: %d, Bomb pass: %t, Bomb kick: %t, Shield: %t\n",
+			playerIcons[p.ID],
+			p.ID[len(p.ID)-4:],
+			p.Team,
+			p.Health,
+			p.Score,
+			p.MaxBombs,
+			p.BlastRadius,
+			p.CanPassBombs,
+			p.CanKickBombs,
+			p.HasShield,
+		)
 	}
 
 	if len(s.Bombs) > 0 {
@@ -294,5 +501,19 @@ func printClassicState(s ClassicStatePayload, ownID string) {
 		}
 	}
 
+	if len(s.Events) > 0 {
+		sb.WriteString("--- EVENTS ---\n")
+		for _, e := range s.Events {
+			fmt.Fprintf(&sb, "%s at (%d,%d)", e.Type, e.Pos.X, e.Pos.Y)
+			if e.PlayerID != "" {
+				fmt.Fprintf(&sb, " | Player: ...%s", e.PlayerID[max(0, len(e.PlayerID)-4):])
+			}
+			if e.OwnerID != "" {
+				fmt.Fprintf(&sb, " | Owner: ...%s", e.OwnerID[max(0, len(e.OwnerID)-4):])
+			}
+			sb.WriteString("\n")
+		}
+	}
+
 	fmt.Print(sb.String())
 }
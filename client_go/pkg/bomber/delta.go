@@ -0,0 +1,110 @@
+package bomber
+
+import "github.com/N3moAhead/bombahead/client_go/pkg/types"
+
+// applyDelta rebuilds the full state from the previous state and a delta.
+// The previous state is not modified. Entries that are new or came into
+// sight are appended, so their order can differ from a full state.
+func applyDelta(prev ClassicStatePayload, delta ClassicStateDeltaPayload) ClassicStatePayload {
+	next := ClassicStatePayload{
+		Tick: delta.Tick,
+		Field: FieldState{
+			Width:  prev.Field.Width,
+			Height: prev.Field.Height,
+			Field:  append([]Tile{}, prev.Field.Field...),
+		},
+		Explosions:  delta.Explosions,
+		Events:      delta.Events,
+		SuddenDeath: delta.SuddenDeath,
+		NextWall:    delta.NextWall,
+	}
+	if next.Explosions == nil {
+		next.Explosions = []types.Vec2{}
+	}
+	if next.Events == nil {
+		next.Events = []GameEvent{}
+	}
+
+	for _, change := range delta.Tiles {
+		next.Field.Field[change.Pos.Y*next.Field.Width+change.Pos.X] = change.Tile
+	}
+
+	removedPlayers := make(map[string]bool, len(delta.RemovedPlayers))
+	for _, id := range delta.RemovedPlayers {
+		removedPlayers[id] = true
+	}
+	changedPlayers := make(map[string]PlayerState, len(delta.Players))
+	for _, player := range delta.Players {
+		changedPlayers[player.ID] = player
+	}
+	next.Players = []PlayerState{}
+	for _, player := range prev.Players {
+		if removedPlayers[player.ID] {
+			continue
+		}
+		if changed, ok := changedPlayers[player.ID]; ok {
+			player = changed
+			delete(changedPlayers, player.ID)
+		}
+		next.Players = append(next.Players, player)
+	}
+	for _, player := range delta.Players {
+		if _, isNew := changedPlayers[player.ID]; isNew {
+			next.Players = append(next.Players, player)
+		}
+	}
+
+	removedBombs := make(map[types.Vec2]bool, len(delta.RemovedBombs))
+	for _, pos := range delta.RemovedBombs {
+		removedBombs[pos] = true
+	}
+	changedBombs := make(map[types.Vec2]BombState, len(delta.Bombs))
+	for _, bomb := range delta.Bombs {
+		changedBombs[bomb.Pos] = bomb
+	}
+	next.Bombs = []BombState{}
+	for _, bomb := range prev.Bombs {
+		if removedBombs[bomb.Pos] {
+			continue
+		}
+		if changed, ok := changedBombs[bomb.Pos]; ok {
+			bomb = changed
+			delete(changedBombs, bomb.Pos)
+		} else {
+			bomb.Fuse--
+		}
+		next.Bombs = append(next.Bombs, bomb)
+	}
+	for _, bomb := range delta.Bombs {
+		if _, isNew := changedBombs[bomb.Pos]; isNew {
+			next.Bombs = append(next.Bombs, bomb)
+		}
+	}
+
+	removedPowerUps := make(map[types.Vec2]bool, len(delta.RemovedPowerUps))
+	for _, pos := range delta.RemovedPowerUps {
+		removedPowerUps[pos] = true
+	}
+	changedPowerUps := make(map[types.Vec2]PowerUpState, len(delta.PowerUps))
+	for _, powerUp := range delta.PowerUps {
+		changedPowerUps[powerUp.Pos] = powerUp
+	}
+	next.PowerUps = []PowerUpState{}
+	for _, powerUp := range prev.PowerUps {
+		if removedPowerUps[powerUp.Pos] {
+			continue
+		}
+		if changed, ok := changedPowerUps[powerUp.Pos]; ok {
+			powerUp = changed
+			delete(changedPowerUps, powerUp.Pos)
+		}
+		next.PowerUps = append(next.PowerUps, powerUp)
+	}
+	for _, powerUp := range delta.PowerUps {
+		if _, isNew := changedPowerUps[powerUp.Pos]; isNew {
+			next.PowerUps = append(next.PowerUps, powerUp)
+		}
+	}
+
+	return next
+}
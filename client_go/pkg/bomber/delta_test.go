@@ -0,0 +1,90 @@
+package bomber
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/N3moAhead/bombahead/client_go/pkg/types"
+)
+
+// The server records the states and deltas of real games in this fixture, so
+// the decoder is tested against what the server actually sends
+const deltaFixture = "../../../server/internal/game/classic/testdata/deltas.json"
+
+type deltaSequence struct {
+	Name   string                     `json:"name"`
+	States []ClassicStatePayload      `json:"states"`
+	Deltas []ClassicStateDeltaPayload `json:"deltas"`
+}
+
+// normalize sorts the entries which applyDelta may append in a different order
+func normalize(state ClassicStatePayload) ClassicStatePayload {
+	comparePos := func(a, b types.Vec2) int {
+		return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.X, b.X))
+	}
+	state.Players = slices.Clone(state.Players)
+	slices.SortFunc(state.Players, func(a, b PlayerState) int { return cmp.Compare(a.ID, b.ID) })
+	state.Bombs = slices.Clone(state.Bombs)
+	slices.SortFunc(state.Bombs, func(a, b BombState) int { return comparePos(a.Pos, b.Pos) })
+	state.PowerUps = slices.Clone(state.PowerUps)
+	slices.SortFunc(state.PowerUps, func(a, b PowerUpState) int { return comparePos(a.Pos, b.Pos) })
+	return state
+}
+
+func TestApplyDelta(t *testing.T) {
+	b, err := os.ReadFile(deltaFixture)
+	if err != nil {
+		t.Fatalf("reading the fixture of the server: %v", err)
+	}
+	var sequences []deltaSequence
+	if err := json.Unmarshal(b, &sequences); err != nil {
+		t.Fatalf("unmarshalling the fixture: %v", err)
+	}
+
+	for _, sequence := range sequences {
+		t.Run(sequence.Name, func(t *testing.T) {
+			if len(sequence.Deltas) == 0 || len(sequence.Deltas) != len(sequence.States)-1 {
+				t.Fatalf("fixture has %d states and %d deltas", len(sequence.States), len(sequence.Deltas))
+			}
+			// Deltas are applied on top of each other like in a game
+			state := sequence.States[0]
+			for i, delta := range sequence.Deltas {
+				state = applyDelta(state, delta)
+
+				want, _ := json.Marshal(normalize(sequence.States[i+1]))
+				got, _ := json.Marshal(normalize(state))
+				if string(want) != string(got) {
+					t.Fatalf("state of tick %d differs from the server:\nwant %s\ngot  %s", delta.Tick, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDeltaKeepsPreviousState(t *testing.T) {
+	prev := ClassicStatePayload{
+		Tick: 1,
+		Field: FieldState{
+			Width:  2,
+			Height: 1,
+			Field:  []Tile{AIR, AIR},
+		},
+		Bombs: []BombState{{Pos: types.Vec2{X: 1, Y: 0}, Fuse: 3}},
+	}
+	delta := ClassicStateDeltaPayload{
+		Tick:     2,
+		BaseTick: 1,
+		Tiles:    []TileChange{{Pos: types.Vec2{X: 0, Y: 0}, Tile: BOX}},
+	}
+
+	next := applyDelta(prev, delta)
+	if next.Field.Field[0] != BOX || next.Bombs[0].Fuse != 2 {
+		t.Errorf("delta was not applied: %+v", next)
+	}
+	if prev.Field.Field[0] != AIR || prev.Bombs[0].Fuse != 3 {
+		t.Errorf("previous state was modified: %+v", prev)
+	}
+}
@@ -21,23 +21,44 @@ const (
 	Error              MessageType = "error"
 	ClassicInput       MessageType = "classic_input"
 	ClassicState       MessageType = "classic_state"
+	ClassicStateDelta  MessageType = "classic_state_delta"
 	GameStart          MessageType = "game_start"
+	Hello              MessageType = "hello"
+	Resume             MessageType = "resume"
+	CreateRoom         MessageType = "create_room"
+	JoinRoom           MessageType = "join_room"
+	LeaveRoom          MessageType = "leave_room"
+	RoomUpdate         MessageType = "room_update"
+	Notice             MessageType = "notice"
 )
 
+// The protocol version and capabilities spoken by this client
+const PROTOCOL_VERSION = 2
+
+var Capabilities = []string{"fog_of_war", "delta_state", "msgpack"}
+
 type GameInfo struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
 
 type WelcomeMessage struct {
-	ClientID     string     `json:"clientId"`
-	CurrentGames []GameInfo `json:"currentGames"`
+	ClientID        string     `json:"clientId"`
+	CurrentGames    []GameInfo `json:"currentGames"`
+	ProtocolVersion int        `json:"protocolVersion"`
+	Capabilities    []string   `json:"capabilities"`
+}
+
+type HelloPayload struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Capabilities    []string `json:"capabilities"`
 }
 
 type PlayerInfo struct {
-	InGame  bool `json:"inGame"`
-	IsReady bool `json:"isReady"`
-	Score   int  `json:"score"`
+	InGame   bool   `json:"inGame"`
+	IsReady  bool   `json:"isReady"`
+	Score    int    `json:"score"`
+	GameMode string `json:"gameMode"`
 }
 
 type LobbyUpdateMessage struct {
@@ -47,18 +68,74 @@ type LobbyUpdateMessage struct {
 type PlayerStatusUpdatePayload struct {
 	IsReady   bool   `json:"isReady"`
 	AuthToken string `json:"authToken"`
+	GameMode  string `json:"gameMode,omitempty"` // Empty for the default game mode
+}
+
+type GameConfig struct {
+	FieldWidth               int     `json:"fieldWidth"`
+	FieldHeight              int     `json:"fieldHeight"`
+	BoxSpawnRate             float64 `json:"boxSpawnRate"`
+	PowerUpSpawnRate         float64 `json:"powerUpSpawnRate"`
+	FuseTicks                int     `json:"fuseTicks"`
+	BombExplosionRadius      int     `json:"bombExplosionRadius"`
+	TickRateMs               int     `json:"tickRateMs"`
+	MaxGameTimeMs            int     `json:"maxGameTimeMs"`
+	WinScorePoints           int     `json:"winScorePoints"`
+	BoxScorePoints           int     `json:"boxScorePoints"`
+	DamageScorePoints        int     `json:"damageScorePoints"`
+	KillScorePoints          int     `json:"killScorePoints"`
+	SurvivalScorePoints      int     `json:"survivalScorePoints"`
+	SurvivalScoreTicks       int     `json:"survivalScoreTicks"`
+	SuddenDeathTicks         int     `json:"suddenDeathTicks"`
+	SuddenDeathIntervalTicks int     `json:"suddenDeathIntervalTicks"`
+	Teams                    int     `json:"teams"`
+	FriendlyFire             bool    `json:"friendlyFire"`
+	ViewRadius               int     `json:"viewRadius"`
+	KeyframeTicks            int     `json:"keyframeTicks"`
+	InitialHealth            int     `json:"initialHealth"`
+	InitialMaxBombs          int     `json:"initialMaxBombs"`
 }
 
 type GameStartPayload struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	GameID      string `json:"gameId"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	GameID      string         `json:"gameId"`
+	Config      GameConfig     `json:"config"`
+	Teams       map[string]int `json:"teams,omitempty"`       // PlayerID -> Team, only set if the game is played in teams
+	ResumeToken string         `json:"resumeToken,omitempty"` // Lets the bot resume the game after the connection dropped
 }
 
-type ErrorMessage struct {
+type CreateRoomPayload struct {
+	MaxPlayers int    `json:"maxPlayers"`         // Between 2 and 4, defaults to 2
+	GameMode   string `json:"gameMode,omitempty"` // Empty for the default game mode
+}
+
+type JoinRoomPayload struct {
+	Code string `json:"code"`
+}
+
+type RoomUpdatePayload struct {
+	Code       string                `json:"code"`
+	Host       string                `json:"host"`
+	MaxPlayers int                   `json:"maxPlayers"`
+	GameMode   string                `json:"gameMode"`
+	InGame     bool                  `json:"inGame"`
+	Players    map[string]PlayerInfo `json:"players"`
+}
+
+type NoticePayload struct {
 	Message string `json:"message"`
 }
 
+type ResumePayload struct {
+	ResumeToken string `json:"resumeToken"`
+}
+
+type ErrorMessage struct {
+	Message     string `json:"message"`
+	CloseReason string `json:"closeReason,omitempty"`
+}
+
 type PlayerMove string
 
 const (
@@ -72,13 +149,21 @@ const (
 
 type ClassicInputPayload struct {
 	Move PlayerMove `json:"move"`
+	Bomb bool       `json:"bomb,omitempty"` // Place a bomb before moving
+	Tick int        `json:"tick"`           // Tick of the state this input answers
 }
 
 type PlayerState struct {
-	ID     string     `json:"id"`
-	Pos    types.Vec2 `json:"pos"`
-	Health int        `json:"health"`
-	Score  int        `json:"score"`
+	ID           string     `json:"id"`
+	Pos          types.Vec2 `json:"pos"`
+	Health       int        `json:"health"`
+	Score        int        `json:"score"`
+	MaxBombs     int        `json:"maxBombs"`
+	BlastRadius  int        `json:"blastRadius"`
+	CanPassBombs bool       `json:"canPassBombs"`
+	CanKickBombs bool       `json:"canKickBombs"`
+	HasShield    bool       `json:"hasShield"`
+	Team         int        `json:"team,omitempty"`
 }
 
 type FieldState struct {
@@ -92,9 +177,66 @@ type BombState struct {
 	Fuse int        `json:"fuse"`
 }
 
+type PowerUpState struct {
+	Pos  types.Vec2  `json:"pos"`
+	Type PowerUpType `json:"type"`
+}
+
+type GameEventType string
+
+const (
+	EVENT_BOMB_PLACED       GameEventType = "bomb_placed"
+	EVENT_BOMB_EXPLODED     GameEventType = "bomb_exploded"
+	EVENT_BOX_DESTROYED     GameEventType = "box_destroyed"
+	EVENT_PLAYER_DAMAGED    GameEventType = "player_damaged"
+	EVENT_PLAYER_ELIMINATED GameEventType = "player_eliminated"
+	EVENT_POWERUP_COLLECTED GameEventType = "powerup_collected"
+)
+
+// GameEvent describes something that happened during the last tick.
+// Only the fields relevant for the event type are set.
+type GameEvent struct {
+	Type        GameEventType `json:"type"`
+	Pos         types.Vec2    `json:"pos"`
+	PlayerID    string        `json:"playerId,omitempty"`    // The player placing the bomb, collecting the power-up or being hit
+	OwnerID     string        `json:"ownerId,omitempty"`     // The owner of the bomb or the player credited for a hit or box
+	ChainParent *types.Vec2   `json:"chainParent,omitempty"` // The bomb that triggered this bomb in a chain reaction
+	Health      int           `json:"health,omitempty"`      // Health left after a hit
+	PowerUp     PowerUpType   `json:"powerUp,omitempty"`     // The collected power-up
+}
+
 type ClassicStatePayload struct {
-	Players    []PlayerState `json:"players"`
-	Field      FieldState    `json:"field"`
-	Bombs      []BombState   `json:"bombs"`
-	Explosions []types.Vec2  `json:"explosions"`
+	Tick        int            `json:"tick"` // Has to be echoed in the input for this state
+	Players     []PlayerState  `json:"players"`
+	Field       FieldState     `json:"field"`
+	Bombs       []BombState    `json:"bombs"`
+	Explosions  []types.Vec2   `json:"explosions"`
+	PowerUps    []PowerUpState `json:"powerUps"`
+	Events      []GameEvent    `json:"events"`
+	SuddenDeath bool           `json:"suddenDeath"`        // True while the walls are closing in
+	NextWall    *types.Vec2    `json:"nextWall,omitempty"` // The tile which becomes a wall next during the sudden death
+}
+
+type TileChange struct {
+	Pos  types.Vec2 `json:"pos"`
+	Tile Tile       `json:"tile"`
+}
+
+// ClassicStateDeltaPayload contains the changes since the state of BaseTick.
+// Bombs that are neither listed in Bombs nor in RemovedBombs keep their
+// position and their fuse goes down by one.
+type ClassicStateDeltaPayload struct {
+	Tick            int            `json:"tick"`
+	BaseTick        int            `json:"baseTick"`
+	Tiles           []TileChange   `json:"tiles,omitempty"`
+	Players         []PlayerState  `json:"players,omitempty"`
+	RemovedPlayers  []string       `json:"removedPlayers,omitempty"`
+	Bombs           []BombState    `json:"bombs,omitempty"`
+	RemovedBombs    []types.Vec2   `json:"removedBombs,omitempty"`
+	Explosions      []types.Vec2   `json:"explosions"`
+	PowerUps        []PowerUpState `json:"powerUps,omitempty"`
+	RemovedPowerUps []types.Vec2   `json:"removedPowerUps,omitempty"`
+	Events          []GameEvent    `json:"events"`
+	SuddenDeath     bool           `json:"suddenDeath"`
+	NextWall        *types.Vec2    `json:"nextWall,omitempty"`
 }
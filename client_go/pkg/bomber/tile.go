@@ -6,4 +6,15 @@ const (
 	AIR  Tile = "AIR"
 	WALL Tile = "WALL"
 	BOX  Tile = "BOX"
+	FOG  Tile = "FOG" // Out of sight, only sent in fog of war games
+)
+
+type PowerUpType string
+
+const (
+	EXTRA_BOMB   PowerUpType = "EXTRA_BOMB"   // One more bomb can be placed at once
+	BLAST_RADIUS PowerUpType = "BLAST_RADIUS" // Explosions reach one more tile
+	BOMB_PASS    PowerUpType = "BOMB_PASS"    // The player can walk over bombs
+	BOMB_KICK    PowerUpType = "BOMB_KICK"    // Walking into a bomb kicks it, it slides until something blocks it
+	SHIELD       PowerUpType = "SHIELD"       // Absorbs the next hit of an explosion
 )
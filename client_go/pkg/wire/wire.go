@@ -0,0 +1,70 @@
+// Package wire encodes and decodes the messages exchanged with the server.
+// Messages are JSON in text frames or, once negotiated, MessagePack in
+// binary frames. Payloads keep the field names of their json tags in both.
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encode serializes a message as MessagePack if binary is set, otherwise as JSON
+func Encode(binary bool, msgType string, payload any) ([]byte, error) {
+	if !binary {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]any{
+			"type":    msgType,
+			"payload": json.RawMessage(payloadBytes),
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	err := enc.Encode(map[string]any{
+		"type":    msgType,
+		"payload": payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads a message from a binary (MessagePack) or text (JSON) frame.
+// The payload is always returned as JSON.
+func Decode(binary bool, data []byte) (string, json.RawMessage, error) {
+	if !binary {
+		var envelope struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		err := json.Unmarshal(data, &envelope)
+		return envelope.Type, envelope.Payload, err
+	}
+
+	var envelope struct {
+		Type    string             `msgpack:"type"`
+		Payload msgpack.RawMessage `msgpack:"payload"`
+	}
+	if err := msgpack.Unmarshal(data, &envelope); err != nil {
+		return "", nil, err
+	}
+	var payload any
+	if len(envelope.Payload) > 0 {
+		if err := msgpack.Unmarshal(envelope.Payload, &payload); err != nil {
+			return "", nil, err
+		}
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	return envelope.Type, payloadBytes, nil
+}
@@ -1,5 +1,10 @@
 import WebSocket from "ws";
-import { MessageType, PlayerMove } from "./message.js";
+import {
+  CAPABILITIES,
+  MessageType,
+  PlayerMove,
+  PROTOCOL_VERSION,
+} from "./message.js";
 import { Tile } from "./tile.js";
 import logger from "./logger.js";
 
@@ -33,6 +38,11 @@ class Bomber {
 
     this.conn.on("open", () => {
       logger.info("Connection established");
+      // Tell the server which protocol we speak before anything else
+      this.send(MessageType.Hello, {
+        protocolVersion: PROTOCOL_VERSION,
+        capabilities: CAPABILITIES,
+      });
       const authToken = process.env.BOMBERMAN_CLIENT_AUTH_TOKEN || "";
       const payload = {
         isReady: true,
@@ -76,6 +86,15 @@ class Bomber {
     switch (msg.type) {
       case MessageType.Welcome: {
         const payload = msg.payload;
+        if (this.bomberID) {
+          // The second welcome answers our hello
+          logger.info(
+            "Negotiated protocol version %d with capabilities %s",
+            payload.protocolVersion,
+            (payload.capabilities || []).join(", "),
+          );
+          break;
+        }
         this.bomberID = payload.clientId;
         logger.success(
           "You connected to the bomberman server: %s",
@@ -113,6 +132,16 @@ class Bomber {
       case MessageType.Error: {
         const errorPayload = msg.payload;
         logger.error("Server Error: %s", errorPayload.message);
+        if (errorPayload.closeReason) {
+          logger.error(
+            "The server is closing the connection: %s",
+            errorPayload.closeReason,
+          );
+        }
+        break;
+      }
+      case MessageType.Notice: {
+        logger.info("Notice from the server: %s", msg.payload.message);
         break;
       }
       case MessageType.GameStart: {
@@ -123,9 +152,13 @@ class Bomber {
       case MessageType.ClassicState: {
         const classicState = msg.payload;
         const nextMove = this.bot.calcNextMove(this.bomberID, classicState);
-        const newPayload = {
-          move: nextMove,
-        };
+        // Bots can return { move, bomb } to place a bomb and move in the same tick
+        const newPayload =
+          typeof nextMove === "object"
+            ? { move: nextMove.move, bomb: nextMove.bomb }
+            : { move: nextMove };
+        // The server rejects inputs for older states
+        newPayload.tick = classicState.tick;
         this.send(MessageType.ClassicInput, newPayload);
         printClassicState(classicState, this.bomberID);
         break;
@@ -184,6 +217,9 @@ function printClassicState(s, ownID) {
           case Tile.BOX:
             grid[y][x] = "📦";
             break;
+          case Tile.FOG:
+            grid[y][x] = "🌫️";
+            break;
         }
       }
     }
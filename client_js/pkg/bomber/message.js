@@ -7,8 +7,14 @@ export const MessageType = {
   ClassicInput: "classic_input",
   ClassicState: "classic_state",
   GameStart: "game_start",
+  Hello: "hello",
+  Notice: "notice",
 };
 
+// The protocol version and capabilities spoken by this client
+export const PROTOCOL_VERSION = 2;
+export const CAPABILITIES = ["fog_of_war"];
+
 export const PlayerMove = {
   DO_NOTHING: "nothing",
   MOVE_UP: "move_up",
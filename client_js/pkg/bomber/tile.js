@@ -2,4 +2,5 @@ export const Tile = {
   AIR: "AIR",
   WALL: "WALL",
   BOX: "BOX",
+  FOG: "FOG", // Out of sight, only sent in fog of war games
 };
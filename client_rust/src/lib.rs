@@ -45,6 +45,20 @@ impl Bomber {
         let bot = Arc::new(bot);
         let bomber_id = Arc::new(Mutex::new(None));
 
+        // Tell the server which protocol we speak before anything else
+        let hello_payload = json!({
+            "type": MessageType::Hello,
+            "payload": HelloPayload {
+                protocol_version: PROTOCOL_VERSION,
+                capabilities: CAPABILITIES.iter().map(|c| c.to_string()).collect(),
+            }
+        });
+        if let Ok(msg_str) = serde_json::to_string(&hello_payload) {
+            if send_tx.send(TungsteniteMessage::Text(msg_str)).is_err() {
+                eprintln!("Failed to send hello");
+            }
+        }
+
         // Initial ready status
         let auth_token =
             std::env::var("BOMBERMAN_CLIENT_AUTH_TOKEN").unwrap_or_else(|_| "".to_string());
@@ -102,6 +116,14 @@ impl Bomber {
         match msg.type_of {
             MessageType::Welcome => {
                 if let Ok(payload) = serde_json::from_value::<WelcomeMessage>(msg.payload) {
+                    if bomber_id.lock().await.is_some() {
+                        // The second welcome answers our hello
+                        println!(
+                            "Negotiated protocol version {} with capabilities {:?}",
+                            payload.protocol_version, payload.capabilities
+                        );
+                        return;
+                    }
                     println!(
                         "You connected to the bomberman server: {}",
                         payload.client_id
@@ -147,6 +169,12 @@ impl Bomber {
             MessageType::Error => {
                 if let Ok(error_payload) = serde_json::from_value::<ErrorMessage>(msg.payload) {
                     eprintln!("Server Error: {}", error_payload.message);
+                    if !error_payload.close_reason.is_empty() {
+                        eprintln!(
+                            "The server is closing the connection: {}",
+                            error_payload.close_reason
+                        );
+                    }
                 }
             }
             MessageType::GameStart => {
@@ -165,7 +193,7 @@ impl Bomber {
                         let next_move = bot.calc_next_move(id, &classic_state);
                         let payload = json!({
                             "type": MessageType::ClassicInput,
-                            "payload": { "move": next_move.to_string() }
+                            "payload": { "move": next_move.to_string(), "tick": classic_state.tick }
                         });
                         if let Ok(msg_str) = serde_json::to_string(&payload) {
                             if tx.send(TungsteniteMessage::Text(msg_str)).is_err() {
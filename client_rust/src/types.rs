@@ -19,8 +19,14 @@ pub enum MessageType {
     ClassicInput,
     ClassicState,
     GameStart,
+    Hello,
 }
 
+/// The protocol version and capabilities spoken by this client
+pub const PROTOCOL_VERSION: i32 = 2;
+// FOG tiles are not supported yet, so fog of war games are off limits
+pub const CAPABILITIES: [&str; 0] = [];
+
 #[derive(Serialize, Deserialize, Debug)]
 pub struct GameInfo {
     pub name: String,
@@ -32,6 +38,17 @@ pub struct GameInfo {
 pub struct WelcomeMessage {
     pub client_id: String,
     pub current_games: Vec<GameInfo>,
+    #[serde(default)]
+    pub protocol_version: i32,
+    #[serde(default)]
+    pub capabilities: Vec<String>,
+}
+
+#[derive(Serialize, Deserialize, Debug)]
+#[serde(rename_all = "camelCase")]
+pub struct HelloPayload {
+    pub protocol_version: i32,
+    pub capabilities: Vec<String>,
 }
 
 #[derive(Serialize, Deserialize, Debug)]
@@ -63,8 +80,11 @@ pub struct GameStartPayload {
 }
 
 #[derive(Serialize, Deserialize, Debug)]
+#[serde(rename_all = "camelCase")]
 pub struct ErrorMessage {
     pub message: String,
+    #[serde(default)]
+    pub close_reason: String,
 }
 
 #[derive(Serialize, Deserialize, Debug, Clone, Copy)]
@@ -99,6 +119,8 @@ impl fmt::Display for PlayerMove {
 pub struct ClassicInputPayload {
     #[serde(rename = "move")]
     pub a_move: PlayerMove,
+    /// Tick of the state this input answers
+    pub tick: i64,
 }
 
 #[derive(Serialize, Deserialize, Debug, Clone)]
@@ -124,6 +146,9 @@ pub struct BombState {
 
 #[derive(Serialize, Deserialize, Debug, Clone)]
 pub struct ClassicStatePayload {
+    /// Has to be echoed in the input for this state
+    #[serde(default)]
+    pub tick: i64,
     pub players: Vec<PlayerState>,
     pub field: FieldState,
     pub bombs: Vec<BombState>,
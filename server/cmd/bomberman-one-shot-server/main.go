@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/N3moAhead/bombahead/server/internal/client"
+	"github.com/N3moAhead/bombahead/server/internal/game"
+	"github.com/N3moAhead/bombahead/server/internal/game/classic"
 	"github.com/N3moAhead/bombahead/server/internal/hub"
 	"github.com/N3moAhead/bombahead/server/pkg/logger"
 	"github.com/google/uuid"
@@ -29,7 +31,42 @@ func main() {
 		log.Warn("History file path env missing")
 	}
 
-	oneShotHub := hub.NewOneShotHub(historyFilePath)
+	// The game config can be provided as a JSON file and/or
+	// overridden by single BOMBERMAN_* environment variables
+	config, err := classic.LoadConfig(os.Getenv("BOMBERMAN_GAME_CONFIG_PATH"))
+	if err != nil {
+		log.Fatal("Invalid game config:", err)
+	}
+
+	// A custom map replaces the default labyrinth
+	var arena *classic.Map
+	if mapPath := os.Getenv("BOMBERMAN_MAP_PATH"); mapPath != "" {
+		arena, err = classic.LoadMap(mapPath)
+		if err != nil {
+			log.Fatal("Invalid map:", err)
+		}
+		log.Info("Using map '%s' from %s", arena.Name, mapPath)
+	}
+
+	gameModes := game.NewRegistry()
+	for _, mode := range []game.Mode{classic.NewMode(config, arena), classic.NewTeamMode(config, arena), classic.NewFogMode(config, arena)} {
+		// A mode can't be played if the map has not enough spawn points for its matches
+		if err := classic.CheckSpawnPoints(mode, config, arena); err != nil {
+			log.Error("Leaving out game mode %s: %v", mode.Name, err)
+			continue
+		}
+		if err := gameModes.Register(mode); err != nil {
+			log.Fatal("Failed to register game mode:", err)
+		}
+	}
+
+	// The played game mode can be selected by its name, defaults to the first registered mode
+	gameMode, ok := gameModes.Get(os.Getenv("BOMBERMAN_GAME_MODE"))
+	if !ok {
+		log.Fatal("Unknown game mode:", os.Getenv("BOMBERMAN_GAME_MODE"))
+	}
+
+	oneShotHub := hub.NewOneShotHub(historyFilePath, gameMode)
 	go oneShotHub.Run()
 
 	mux := http.NewServeMux()
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/N3moAhead/bombahead/server/internal/hub"
+)
+
+type noticeRequest struct {
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// registerAdminAPI adds the endpoints for the server operators.
+// Every request has to send the admin token as bearer token.
+func registerAdminAPI(hubInstance *hub.Hub, token string) {
+	http.HandleFunc("GET /admin/clients", requireAdminToken(token, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, hubInstance.Clients())
+	}))
+
+	http.HandleFunc("GET /admin/games", requireAdminToken(token, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, hubInstance.Games())
+	}))
+
+	http.HandleFunc("POST /admin/games/{id}/stop", requireAdminToken(token, func(w http.ResponseWriter, r *http.Request) {
+		if !hubInstance.StopGame(r.PathValue("id")) {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "unknown game " + r.PathValue("id")})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	http.HandleFunc("POST /admin/clients/{id}/kick", requireAdminToken(token, func(w http.ResponseWriter, r *http.Request) {
+		if !hubInstance.KickClient(r.PathValue("id")) {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "unknown client " + r.PathValue("id")})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	http.HandleFunc("POST /admin/notice", requireAdminToken(token, func(w http.ResponseWriter, r *http.Request) {
+		var notice noticeRequest
+		if err := json.NewDecoder(r.Body).Decode(&notice); err != nil || strings.TrimSpace(notice.Message) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: `expected a body like {"message": "..."}`})
+			return
+		}
+		hubInstance.BroadcastNotice(notice.Message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+// requireAdminToken rejects requests without the correct bearer token
+func requireAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			l.Warn("Rejected admin request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid admin token"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		l.Error("Failed to write admin response: %v", err)
+	}
+}
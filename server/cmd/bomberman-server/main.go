@@ -4,15 +4,23 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/N3moAhead/bombahead/server/internal/client"
+	"github.com/N3moAhead/bombahead/server/internal/game"
+	"github.com/N3moAhead/bombahead/server/internal/game/classic"
 	"github.com/N3moAhead/bombahead/server/internal/hub"
 	"github.com/N3moAhead/bombahead/server/pkg/logger"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var addr = flag.String("addr", ":8038", "http service address")
+var mapPath = flag.String("map", "", "path to a custom map file, the default labyrinth is used if empty")
+var matchSize = flag.Int("match-size", classic.MIN_PLAYERS, "players per game of the classic and fog modes, 2 for 1v1 up to 4 for a free-for-all")
+var adminToken = flag.String("admin-token", os.Getenv("BOMBERMAN_ADMIN_TOKEN"), "bearer token for the admin API on /admin, the API is disabled if empty")
+var matchByScore = flag.Bool("match-by-score", false, "match players with similar scores instead of first come, first served")
 
 var l = logger.New("[Live-Server]")
 
@@ -29,9 +37,49 @@ var Upgrader = websocket.Upgrader{
 func main() {
 	flag.Parse()
 
-	hubInstance := hub.NewHub()
+	var arena *classic.Map
+	if *mapPath != "" {
+		var err error
+		arena, err = classic.LoadMap(*mapPath)
+		if err != nil {
+			l.Fatal("Invalid map:", err)
+		}
+	}
+
+	if *matchSize < classic.MIN_PLAYERS || *matchSize > classic.MAX_PLAYERS {
+		l.Fatal("The match size has to be between", classic.MIN_PLAYERS, "and", classic.MAX_PLAYERS)
+	}
+
+	classicMode := classic.NewMode(classic.DefaultConfig(), arena)
+	classicMode.MatchSize = *matchSize
+	fogMode := classic.NewFogMode(classic.DefaultConfig(), arena)
+	fogMode.MatchSize = *matchSize
+
+	gameModes := game.NewRegistry()
+	for _, mode := range []game.Mode{classicMode, classic.NewTeamMode(classic.DefaultConfig(), arena), fogMode} {
+		// A mode can't be played if the map has not enough spawn points for its matches
+		if err := classic.CheckSpawnPoints(mode, classic.DefaultConfig(), arena); err != nil {
+			l.Error("Leaving out game mode %s: %v", mode.Name, err)
+			continue
+		}
+		if err := gameModes.Register(mode); err != nil {
+			l.Fatal("Failed to register game mode:", err)
+		}
+	}
+	if _, ok := gameModes.Default(); !ok {
+		l.Fatal("No game mode can be played on this map")
+	}
+
+	hubInstance := hub.NewHub(gameModes, *matchByScore)
 	go hubInstance.Run()
 
+	if *adminToken != "" {
+		registerAdminAPI(hubInstance, *adminToken)
+		l.Info("Admin API is available on /admin")
+	} else {
+		l.Info("No admin token is set, the admin API is disabled")
+	}
+
 	// Register the WebSocket handler
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		// Pass the single hub instance to the handler
@@ -47,6 +95,23 @@ func main() {
 		client.StartPumps()
 	})
 
+	// Spectators use their own endpoint so they never end up in the lobby
+	http.HandleFunc("/spectate", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			l.Error("WebSocket upgrade error: %v", err)
+			return
+		}
+		l.Success("Spectator connected from: %s", conn.RemoteAddr())
+
+		spectator := client.NewClient(hubInstance, conn, uuid.NewString())
+		hubInstance.Spectate <- spectator
+		spectator.StartPumps()
+	})
+
+	// Prometheus scrapes the metrics of the hub, the clients and the games here
+	http.Handle("/metrics", promhttp.Handler())
+
 	// Simple handler for the root path
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
@@ -54,7 +119,7 @@ func main() {
 			return
 		}
 		w.WriteHeader(http.StatusOK)
-		_, err := w.Write([]byte("[BOMBERMAN-SERVER] is running. Connect via WebSocket on /ws"))
+		_, err := w.Write([]byte("[BOMBERMAN-SERVER] is running. Connect via WebSocket on /ws or watch games on /spectate"))
 		if err != nil {
 			l.Errorln("Failed to write status message")
 		}
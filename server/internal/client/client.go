@@ -11,6 +11,8 @@ import (
 	"github.com/N3moAhead/bombahead/server/internal/message"
 	"github.com/N3moAhead/bombahead/server/pkg/logger"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 const (
@@ -22,19 +24,51 @@ const (
 
 var log = logger.New("[Client]")
 
+var (
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bomberman_messages_received_total",
+		Help: "Messages received from clients by message type",
+	}, []string{"type"})
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bomberman_messages_sent_total",
+		Help: "Messages queued for clients by message type",
+	}, []string{"type"})
+	messagesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bomberman_messages_dropped_total",
+		Help: "Messages dropped because the send buffer of a client was full",
+	}, []string{"type"})
+	websocketErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bomberman_websocket_errors_total",
+		Help: "Failed websocket operations by operation",
+	}, []string{"op"})
+)
+
 // Client is a WebSocket client that communicates with a Hub
 type Client struct {
 	Hub       hub.HubConnection
 	Conn      *websocket.Conn
-	Send      chan []byte
+	Send      chan Frame
 	ID        string
 	Score     int
 	isReady   bool
 	gameID    string
-	authToken string // Is just important for async bot games and the one shot hub
+	authToken string       // Is just important for async bot games and the one shot hub
+	gameMode  string       // Name of the game mode the client wants to play
+	stateMu   sync.RWMutex // Guards the fields above, a resumed client changes them while its pumps run
 	sendMu    sync.RWMutex
 	closeOnce sync.Once
 	isClosed  bool
+
+	protocolMu      sync.RWMutex
+	protocolVersion int             // Negotiated protocol version
+	capabilities    map[string]bool // Negotiated capabilities
+	closeReason     string          // Reason sent in the close frame, empty for a normal closure
+}
+
+// Frame is an encoded message waiting to be written to the websocket
+type Frame struct {
+	Binary bool // MessagePack in a binary frame instead of JSON in a text frame
+	Data   []byte
 }
 
 // Assure Client implements the interface from the hub package
@@ -45,9 +79,12 @@ func NewClient(hub hub.HubConnection, conn *websocket.Conn, id string) *Client {
 	return &Client{
 		Hub:     hub,
 		Conn:    conn,
-		Send:    make(chan []byte, 256),
+		Send:    make(chan Frame, 256),
 		ID:      id,
 		isReady: false,
+
+		protocolVersion: message.MIN_PROTOCOL_VERSION,
+		capabilities:    make(map[string]bool),
 	}
 }
 
@@ -59,42 +96,121 @@ func (c *Client) StartPumps() {
 
 // GetID returns the client's unique identifier
 func (c *Client) GetID() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.ID
 }
 
 func (c *Client) SetAuthToken(authToken string) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
 	c.authToken = authToken
 }
 
 func (c *Client) GetAuthToken() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.authToken
 }
 
 // IsReady indicates if the client is ready to start a game
 func (c *Client) IsReady() bool {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.isReady
 }
 
 // SetReady sets the client's ready status
 func (c *Client) SetReady(ready bool) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
 	c.isReady = ready
 }
 
 // GetScore returns the client's current score
 func (c *Client) GetScore() int {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.Score
 }
 
 // IncrementScore adds a value to the client's score
 func (c *Client) IncrementScore(delta int) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
 	c.Score += delta
 }
 
 // SetGameID sets the ID of the game the client is currently in
 func (c *Client) SetGameID(id string) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
 	c.gameID = id
 }
 
+// SetGameMode sets the name of the game mode the client wants to play
+func (c *Client) SetGameMode(mode string) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.gameMode = mode
+}
+
+// GetGameMode returns the name of the game mode the client wants to play
+func (c *Client) GetGameMode() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.gameMode
+}
+
+// SetProtocol stores the protocol version and capabilities negotiated with the client
+func (c *Client) SetProtocol(version int, capabilities []string) {
+	c.protocolMu.Lock()
+	defer c.protocolMu.Unlock()
+	c.protocolVersion = version
+	c.capabilities = make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		c.capabilities[capability] = true
+	}
+}
+
+// GetProtocolVersion returns the negotiated protocol version
+func (c *Client) GetProtocolVersion() int {
+	c.protocolMu.RLock()
+	defer c.protocolMu.RUnlock()
+	return c.protocolVersion
+}
+
+// HasCapability reports whether the capability was negotiated with the client
+func (c *Client) HasCapability(capability string) bool {
+	c.protocolMu.RLock()
+	defer c.protocolMu.RUnlock()
+	return c.capabilities[capability]
+}
+
+// Resume takes over the identity of a client whose connection dropped
+// during a game, so the game and the lobby see the same player again
+func (c *Client) Resume(previous hub.Client) {
+	id, score, authToken, gameMode := previous.GetID(), previous.GetScore(), previous.GetAuthToken(), previous.GetGameMode()
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.ID = id
+	c.Score = score
+	c.authToken = authToken
+	c.gameMode = gameMode
+}
+
+// CloseWithReason closes the client like Close but tells the
+// client why the connection is closed in the close frame
+func (c *Client) CloseWithReason(reason string) {
+	c.sendMu.Lock()
+	if !c.isClosed {
+		c.closeReason = reason
+	}
+	c.sendMu.Unlock()
+	c.Close()
+}
+
 // Close closes the client's send channel. The connection itself is closed
 // by the read/write pumps when they exit
 func (c *Client) Close() {
@@ -109,33 +225,34 @@ func (c *Client) Close() {
 	})
 }
 
-// SendMessage formats and sends a structured message to the client
+// SendMessage formats and sends a structured message to the client. Clients
+// which negotiated MessagePack receive binary frames, all others JSON
 func (c *Client) SendMessage(msgType message.MessageType, payload any) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		log.Error("Error marshalling payload for client %s: %v", c.ID, err)
-		return err
+	frame := Frame{Binary: c.HasCapability(message.CAPABILITY_MSGPACK)}
+	var err error
+	if frame.Binary {
+		frame.Data, err = message.EncodeMsgpack(msgType, payload)
+	} else {
+		frame.Data, err = message.EncodeJSON(msgType, payload)
 	}
-	message := message.Message{
-		Type:    msgType,
-		Payload: json.RawMessage(payloadBytes),
-	}
-	messageBytes, err := json.Marshal(message)
 	if err != nil {
-		log.Error("Error marshalling message for client %s: %v", c.ID, err)
+		log.Error("Error encoding message for client %s: %v", c.GetID(), err)
 		return err
 	}
 
 	c.sendMu.RLock()
 	defer c.sendMu.RUnlock()
 	if c.isClosed {
-		return fmt.Errorf("client %s is closed", c.ID)
+		return fmt.Errorf("client %s is closed", c.GetID())
 	}
 
 	select {
-	case c.Send <- messageBytes:
+	case c.Send <- frame:
+		messagesSent.WithLabelValues(string(msgType)).Inc()
 	default:
-		log.Error("Client %s send buffer full. Dropping message.", c.ID)
+		// The caller has to know, a dropped state breaks the deltas that follow it
+		messagesDropped.WithLabelValues(string(msgType)).Inc()
+		return fmt.Errorf("send buffer of client %s is full, dropped the %s message", c.GetID(), msgType)
 	}
 	return nil
 }
@@ -147,7 +264,7 @@ func (c *Client) ReadPump() {
 	defer func() {
 		c.Hub.UnregisterClient(c)
 		c.Conn.Close()
-		log.Info("Client %s disconnected (readPump closed)", c.ID)
+		log.Info("Client %s disconnected (readPump closed)", c.GetID())
 	}()
 	c.Conn.SetReadLimit(maxMessageSize)
 	err := c.Conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -164,20 +281,33 @@ func (c *Client) ReadPump() {
 		})
 
 	for {
-		_, messageBytes, err := c.Conn.ReadMessage()
+		frameType, messageBytes, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Error("error reading message for client %s: %v", c.ID, err)
+				websocketErrors.WithLabelValues("read").Inc()
+				log.Error("error reading message for client %s: %v", c.GetID(), err)
 			}
 			break
 		}
 
 		var msg message.Message
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
-			log.Error("error unmarshalling message from client %s: %v", c.ID, err)
+		if frameType == websocket.BinaryMessage {
+			msg, err = message.DecodeMsgpack(messageBytes)
+		} else {
+			err = json.Unmarshal(messageBytes, &msg)
+		}
+		if err != nil {
+			websocketErrors.WithLabelValues("decode").Inc()
+			log.Error("error unmarshalling message from client %s: %v", c.GetID(), err)
 			continue
 		}
 
+		// Clients choose the type, so unknown ones share a label to keep the series bounded
+		if msg.Type.IsKnown() {
+			messagesReceived.WithLabelValues(string(msg.Type)).Inc()
+		} else {
+			messagesReceived.WithLabelValues("unknown").Inc()
+		}
 		c.Hub.HandleIncomingMessage(c, msg)
 	}
 }
@@ -188,25 +318,36 @@ func (c *Client) WritePump() {
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
-		log.Info("Client %s writePump closed", c.ID)
+		log.Info("Client %s writePump closed", c.GetID())
 	}()
 	for {
 		select {
-		case message, ok := <-c.Send:
+		case frame, ok := <-c.Send:
 			err := c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err != nil {
 				log.Errorln("WriteDeadline is due and now corrupted", err)
 			}
 			if !ok {
-				err := c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeMessage := []byte{}
+				c.sendMu.RLock()
+				if c.closeReason != "" {
+					closeMessage = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, c.closeReason)
+				}
+				c.sendMu.RUnlock()
+				err := c.Conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				if err != nil {
 					log.Errorln("Failed to write messsage to client", err)
 				}
-				log.Info("Client %s send channel closed by hub", c.ID)
+				log.Info("Client %s send channel closed by hub", c.GetID())
 				return
 			}
-			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Error("error writing message to client %s: %v", c.ID, err)
+			frameType := websocket.TextMessage
+			if frame.Binary {
+				frameType = websocket.BinaryMessage
+			}
+			if err := c.Conn.WriteMessage(frameType, frame.Data); err != nil {
+				websocketErrors.WithLabelValues("write").Inc()
+				log.Error("error writing message to client %s: %v", c.GetID(), err)
 				return
 			}
 		case <-ticker.C:
@@ -215,7 +356,8 @@ func (c *Client) WritePump() {
 				log.Errorln("WriteDeadline has expired and is now corrupted: ", err)
 			}
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Error("error sending ping to client %s: %v", c.ID, err)
+				websocketErrors.WithLabelValues("ping").Inc()
+				log.Error("error sending ping to client %s: %v", c.GetID(), err)
 				return
 			}
 		}
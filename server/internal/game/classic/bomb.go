@@ -3,18 +3,76 @@ package classic
 import "github.com/N3moAhead/bombahead/server/pkg/types"
 
 type Bomb struct {
-	Pos  types.Vec2 `json:"pos"`
-	Fuse int        `json:"fuse"` // Countdown each tick explodes at 0
+	Pos     types.Vec2 `json:"pos"`
+	Fuse    int        `json:"fuse"`    // Countdown each tick explodes at 0
+	OwnerID string     `json:"ownerId"` // ID of the player who placed the bomb
+	Radius  int        `json:"radius"`  // Explosion radius including the center
+
+	sequence  int        // Placement order, bombs are updated in this order
+	direction types.Vec2 // Direction the bomb slides in after a kick, zero while it rests
+}
+
+// Explosion is a single tile hit by an explosion in the current tick
+type Explosion struct {
+	Pos     types.Vec2
+	OwnerID string // ID of the player credited for the explosion
 }
 
-func NewBomb(pos types.Vec2) *Bomb {
+func NewBomb(pos types.Vec2, ownerID string, fuse int, radius int, sequence int) *Bomb {
 	return &Bomb{
-		Pos:  pos,
-		Fuse: fuse_ticks,
+		Pos:      pos,
+		Fuse:     fuse,
+		OwnerID:  ownerID,
+		Radius:   radius,
+		sequence: sequence,
 	}
 }
 
-func (c *Classic) containsBomb(pos types.Vec2) bool {
-	_, ok := c.bombs[pos.String()]
+func (s *Simulation) containsBomb(pos types.Vec2) bool {
+	_, ok := s.bombs[pos.String()]
 	return ok
 }
+
+// kick sets the bomb in motion, it slides one tile per tick
+func (b *Bomb) kick(dir types.Vec2) {
+	b.direction = dir
+}
+
+// slideBombs moves every kicked bomb one tile further in placement order.
+// A bomb stops in front of walls, boxes, other bombs and living players.
+func (s *Simulation) slideBombs() {
+	for _, bomb := range s.orderedBombs() {
+		if bomb.direction == (types.Vec2{}) {
+			continue
+		}
+		next := bomb.Pos.Add(bomb.direction)
+		if s.field.isTileBlocked(next.X, next.Y) || s.containsBomb(next) || s.containsLivingPlayer(next) {
+			bomb.direction = types.Vec2{}
+			continue
+		}
+		delete(s.bombs, bomb.Pos.String())
+		bomb.Pos = next
+		s.bombs[bomb.Pos.String()] = bomb
+	}
+}
+
+// containsLivingPlayer reports whether a player who is still alive stands on the tile
+func (s *Simulation) containsLivingPlayer(pos types.Vec2) bool {
+	for _, player := range s.players {
+		if player.Health > 0 && player.Pos == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// activeBombCount returns the number of bombs of a player that have not exploded yet
+func (s *Simulation) activeBombCount(playerID string) int {
+	count := 0
+	for _, bomb := range s.bombs {
+		if bomb.OwnerID == playerID {
+			count += 1
+		}
+	}
+	return count
+}
@@ -10,53 +10,65 @@ import (
 	"github.com/N3moAhead/bombahead/server/internal/game"
 	"github.com/N3moAhead/bombahead/server/internal/message"
 	"github.com/N3moAhead/bombahead/server/pkg/logger"
-	"github.com/N3moAhead/bombahead/server/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var log = logger.New("[Classic]")
 
+// SPECTATOR_DELAY is how far spectators of fog of war games lag behind the players
+const SPECTATOR_DELAY = 10 * time.Second
+
+// tickDuration covers simulating a tick and handing its states to the clients
+var tickDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name: "bomberman_tick_duration_seconds",
+	Help: "Time spent simulating a tick and sending its states",
+	// A tick normally takes well below a millisecond
+	Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+})
+
+// Classic runs a Simulation in real time. It collects the inputs
+// of the connected players and steps the simulation on every tick.
 type Classic struct {
 	gameFinisher game.GameFinisher
 	stopChan     chan bool
 
 	gameID          string
-	players         map[string]*Player     // ClientID -> ClassicPlayer
-	playerMap       map[string]game.Player // ClientID -> game.Player
+	sim             *Simulation
+	playerMap       map[string]game.Player         // ClientID -> game.Player
+	nextInputs      map[string]PlayerInput         // ClientID -> Input for the next tick
+	lastStates      map[string]ClassicStatePayload // ClientID -> Last state sent to a client receiving deltas
+	spectators      map[string]game.Player         // ClientID -> Spectator watching the game
+	delayedStates   []ClassicStatePayload          // Full states held back from the spectators of fog of war games
+	inputs          *inputTracker
 	playerMux       sync.RWMutex
-	history         *History
 	historyFilePath string
 
-	field      *Field
-	bombs      map[string]*Bomb      // Bomb.Pos -> Bomb
-	explosions map[string]types.Vec2 // Pos -> Vec2(Pos of the Bomb)
+	isRunning     bool
+	stopRequested bool // Stop was called before the game loop started
+	minPlayers    int
 
-	isRunning  bool
-	minPlayers int
-	maxPLayers int
-	isTimeOut  bool
-
-	ticker       *time.Ticker
-	lastTickTime time.Time // for delta time
+	ticker *time.Ticker
 }
 
-func NewClassic(finisher game.GameFinisher, id string, historyFilePath string) *Classic {
+// NewClassic creates a new classic game. If arena is nil the
+// default labyrinth is generated, otherwise the field is built from the map.
+func NewClassic(finisher game.GameFinisher, id string, historyFilePath string, config Config, arena *Map) *Classic {
 	return &Classic{
 		gameFinisher: finisher,
 		stopChan:     make(chan bool),
 
 		gameID:          id,
-		players:         make(map[string]*Player),
+		sim:             NewSimulation(config, arena, time.Now().UnixNano()),
 		playerMap:       make(map[string]game.Player),
+		nextInputs:      make(map[string]PlayerInput),
+		lastStates:      make(map[string]ClassicStatePayload),
+		spectators:      make(map[string]game.Player),
+		inputs:          newInputTracker(),
 		historyFilePath: historyFilePath,
 
-		field:      NewField(),
-		bombs:      make(map[string]*Bomb),
-		explosions: make(map[string]types.Vec2),
-
 		isRunning:  false,
 		minPlayers: MIN_PLAYERS,
-		maxPLayers: MAX_PLAYERS,
-		isTimeOut:  false,
 	}
 }
 
@@ -64,45 +76,33 @@ func (c *Classic) GetID() string {
 	return c.gameID
 }
 
+// GetConfig returns the configuration the game is played with
+func (c *Classic) GetConfig() any {
+	return c.sim.Config()
+}
+
+// GetTeams returns the team of every player or nil in free for all games
+func (c *Classic) GetTeams() map[string]int {
+	c.playerMux.RLock()
+	defer c.playerMux.RUnlock()
+	return c.sim.Teams()
+}
+
+// GetTick returns the number of ticks simulated so far
+func (c *Classic) GetTick() int {
+	c.playerMux.RLock()
+	defer c.playerMux.RUnlock()
+	return c.sim.Tick()
+}
+
 func (c *Classic) AddPlayer(player game.Player) error {
 	c.playerMux.Lock()
 	defer c.playerMux.Unlock()
 
-	if len(c.players) >= c.maxPLayers {
-		return fmt.Errorf(
-			"[Game %s] Already full, can't add more players (%d/%d)!\n",
-			c.gameID,
-			len(c.players),
-			c.maxPLayers,
-		)
-	}
-
 	playerID := player.GetID()
-	if _, exists := c.players[playerID]; exists {
-		return fmt.Errorf("[Game %s] Player %s already exists.\n", c.gameID, playerID)
-	}
-
-	// We define the spawn points for the players in the corners of the map
-	spawnPoints := []types.Vec2{
-		types.NewVec2(1, 1),                          // Top-Left
-		types.NewVec2(field_width-2, 1),              // Top-Right
-		types.NewVec2(1, field_height-2),             // Bottom-Left
-		types.NewVec2(field_width-2, field_height-2), // Bottom-Right
+	if err := c.sim.AddPlayer(playerID, player.GetAuthToken()); err != nil {
+		return fmt.Errorf("[Game %s] Can't add player %s: %w", c.gameID, playerID, err)
 	}
-
-	// Assign a spawn point based on the number of players already in the game.
-	playerIndex := len(c.players)
-	spawnPos := spawnPoints[playerIndex]
-
-	newPlayer := &Player{
-		ID:        playerID,
-		Pos:       spawnPos,
-		Score:     0,
-		Health:    initial_health,
-		NextMove:  NO_INPUT_DEFINED,
-		AuthToken: player.GetAuthToken(),
-	}
-	c.players[playerID] = newPlayer
 	c.playerMap[playerID] = player
 
 	log.Success("Player %s added. (Game %s)\n", playerID, c.gameID)
@@ -114,16 +114,17 @@ func (c *Classic) RemovePlayer(player game.Player) {
 	defer c.playerMux.Unlock()
 
 	playerID := player.GetID()
-	if _, ok := c.players[playerID]; ok {
-		delete(c.players, playerID)
+	if c.sim.RemovePlayer(playerID) {
 		delete(c.playerMap, playerID)
+		delete(c.nextInputs, playerID)
+		delete(c.lastStates, playerID)
 		log.Info("[Game %s] Player %s removed.\n", c.gameID, playerID)
 
-		if len(c.players) < c.minPlayers && c.isRunning {
+		if c.sim.PlayerCount() < c.minPlayers && c.isRunning {
 			log.Warn(
 				"[Game %s] Not enough players remaining (%d/%d). Stopping game.\n",
 				c.gameID,
-				len(c.players),
+				c.sim.PlayerCount(),
 				c.minPlayers,
 			)
 			go c.Stop()
@@ -131,20 +132,101 @@ func (c *Classic) RemovePlayer(player game.Player) {
 	}
 }
 
+// DisconnectPlayer stops sending states to a player whose connection dropped.
+// The player stays in the game and idles until it reconnects or is removed.
+func (c *Classic) DisconnectPlayer(player game.Player) {
+	c.playerMux.Lock()
+	defer c.playerMux.Unlock()
+
+	playerID := player.GetID()
+	delete(c.playerMap, playerID)
+	delete(c.nextInputs, playerID)
+	delete(c.lastStates, playerID)
+	log.Warn("[Game %s] Player %s disconnected, waiting for it to reconnect.\n", c.gameID, playerID)
+}
+
+// ReconnectPlayer attaches a new connection to a player that is still in the
+// game. The first state sent to the new connection is always a full state.
+func (c *Classic) ReconnectPlayer(player game.Player) error {
+	c.playerMux.Lock()
+	defer c.playerMux.Unlock()
+
+	playerID := player.GetID()
+	if !c.sim.HasPlayer(playerID) {
+		return fmt.Errorf("[Game %s] Can't reconnect player %s: not in the game", c.gameID, playerID)
+	}
+	c.playerMap[playerID] = player
+	delete(c.lastStates, playerID)
+
+	log.Success("Player %s reconnected. (Game %s)\n", playerID, c.gameID)
+	return nil
+}
+
+// AddSpectator sends every following state to the spectator as well.
+// Spectators always see the whole field, even in fog of war games.
+func (c *Classic) AddSpectator(spectator game.Player) {
+	c.playerMux.Lock()
+	defer c.playerMux.Unlock()
+
+	c.spectators[spectator.GetID()] = spectator
+	delete(c.lastStates, spectator.GetID())
+	log.Info("[Game %s] Spectator %s added.\n", c.gameID, spectator.GetID())
+}
+
+// RemoveSpectator stops sending states to the spectator
+func (c *Classic) RemoveSpectator(spectator game.Player) {
+	c.playerMux.Lock()
+	defer c.playerMux.Unlock()
+
+	delete(c.spectators, spectator.GetID())
+	delete(c.lastStates, spectator.GetID())
+	log.Info("[Game %s] Spectator %s removed.\n", c.gameID, spectator.GetID())
+}
+
+// spectatorStates holds back the full state of the tick and returns the
+// states that are due for the spectators. Games without fog of war are
+// watched live, otherwise a player could watch its own game to see through
+// the fog. Once the game is over every held back state is due.
+// The caller has to hold the playerMux.
+func (c *Classic) spectatorStates(state ClassicStatePayload, gameOver bool) []ClassicStatePayload {
+	if !c.sim.HasFogOfWar() {
+		return []ClassicStatePayload{state}
+	}
+
+	c.delayedStates = append(c.delayedStates, state)
+	if gameOver {
+		due := c.delayedStates
+		c.delayedStates = nil
+		return due
+	}
+	delayTicks := int(SPECTATOR_DELAY / c.sim.Config().TickRate())
+	if len(c.delayedStates) <= delayTicks {
+		return nil
+	}
+	due := c.delayedStates[0]
+	c.delayedStates = c.delayedStates[1:]
+	return []ClassicStatePayload{due}
+}
+
 func (c *Classic) Start() {
 	c.playerMux.Lock()
-	if len(c.players) < c.minPlayers {
+	if c.sim.PlayerCount() < c.minPlayers {
 		c.playerMux.Unlock()
-		log.Error("[Game %s] Cannot start, not enough players (%d/%d).", c.gameID, len(c.players), c.minPlayers)
+		log.Error("[Game %s] Cannot start, not enough players (%d/%d).", c.gameID, c.sim.PlayerCount(), c.minPlayers)
 		go c.Stop()
 		return
 	}
 
 	c.isRunning = true
+	if c.stopRequested {
+		c.playerMux.Unlock()
+		log.Info("[Game %s] Was stopped before the game loop started.", c.gameID)
+		c.Stop()
+		return
+	}
 	// Initialize history recording at the start of the game
-	c.history = NewHistory(c.getGameState().Field)
-	c.lastTickTime = time.Now()
-	c.ticker = time.NewTicker(TICK_RATE)
+	c.sim.RecordHistory()
+	c.ticker = time.NewTicker(c.sim.Config().TickRate())
 	c.playerMux.Unlock()
 
 	log.Info("[Game %s] Starting game loop.", c.gameID)
@@ -155,12 +237,10 @@ func (c *Classic) Start() {
 		log.Info("[Game %s] Game loop stopped.", c.gameID)
 	}()
 
-	maxGameTimer := time.NewTimer(MAX_GAME_TIME)
-	defer maxGameTimer.Stop()
-
 	for {
 		select {
 		case <-c.ticker.C:
+			tickStart := time.Now()
 			// A snapshot of players is created to
 			// avoid holding the lock during network I/O
 			c.playerMux.RLock()
@@ -168,21 +248,62 @@ func (c *Classic) Start() {
 			for _, p := range c.playerMap {
 				playersToMessage = append(playersToMessage, p)
 			}
+			spectatorsToMessage := make([]game.Player, 0, len(c.spectators))
+			for _, s := range c.spectators {
+				spectatorsToMessage = append(spectatorsToMessage, s)
+			}
 			c.playerMux.RUnlock()
 
 			// Lock the mutex to ensure exclusive access to
 			// the game state during the update.
 			c.playerMux.Lock()
-			destroyedBoxes := c.update()
-			c.history.RecordTick(c.players, c.bombs, c.explosions, destroyedBoxes)
-			gameState := c.getGameState()
-			gameOver := c.isGameOver()
-			c.resetPlayerInputs()
+			// Before the first tick no state was sent, so nobody could miss it
+			if c.sim.Tick() > 0 {
+				for _, p := range playersToMessage {
+					if _, ok := c.nextInputs[p.GetID()]; !ok {
+						c.inputs.tickMissed(p.GetID())
+					}
+				}
+			}
+			gameState, events := c.sim.Step(c.nextInputs)
+			c.nextInputs = make(map[string]PlayerInput)
+			if history := c.sim.History(); history != nil {
+				latencies := make(map[string]int)
+				for playerID, latency := range c.inputs.latenciesMs() {
+					latencies[c.sim.HistoryKey(playerID)] = latency
+				}
+				history.RecordLatencies(latencies)
+			}
+			// With fog of war every player gets its own view of the game
+			playerStates := make(map[string]ClassicStatePayload, len(playersToMessage))
+			if c.sim.HasFogOfWar() {
+				for _, p := range playersToMessage {
+					playerStates[p.GetID()] = c.sim.StateFor(p.GetID())
+				}
+			}
+			stateMessages := make(map[string]stateMessage, len(playersToMessage))
+			for _, p := range playersToMessage {
+				playerState, ok := playerStates[p.GetID()]
+				if !ok {
+					playerState = gameState
+				}
+				stateMessages[p.GetID()] = c.stateMessageFor(p, playerState)
+			}
+			dueStates := c.spectatorStates(gameState, events.GameOver)
+			spectatorMessages := make(map[string][]stateMessage, len(spectatorsToMessage))
+			for _, s := range spectatorsToMessage {
+				for _, state := range dueStates {
+					spectatorMessages[s.GetID()] = append(spectatorMessages[s.GetID()], c.stateMessageFor(s, state))
+				}
+			}
+			c.inputs.stateSent()
 			c.playerMux.Unlock()
 
 			// Now, with the mutex released, we can safely send the new state to all players.
 			for _, p := range playersToMessage {
-				if err := p.SendMessage(message.ClassicState, gameState); err != nil {
+				stateMsg := stateMessages[p.GetID()]
+				if err := p.SendMessage(stateMsg.msgType, stateMsg.payload); err != nil {
+					c.forgetLastState(p.GetID())
 					log.Error(
 						"[Game %s] Error sending state to player %s: %v",
 						c.gameID,
@@ -191,14 +312,28 @@ func (c *Classic) Start() {
 					)
 				}
 			}
-			if gameOver {
+			for _, s := range spectatorsToMessage {
+				for _, stateMsg := range spectatorMessages[s.GetID()] {
+					if err := s.SendMessage(stateMsg.msgType, stateMsg.payload); err != nil {
+						c.forgetLastState(s.GetID())
+						log.Error(
+							"[Game %s] Error sending state to spectator %s: %v",
+							c.gameID,
+							s.GetID(),
+							err,
+						)
+						// The following deltas build on the state that was not sent
+						break
+					}
+				}
+			}
+			tickDuration.Observe(time.Since(tickStart).Seconds())
+			if events.GameOver {
+				// No more ticks are simulated after the game is over
+				// so the history ends with the deciding tick
 				go c.Stop()
+				return
 			}
-		case <-maxGameTimer.C:
-			c.playerMux.Lock()
-			c.isTimeOut = true
-			c.playerMux.Unlock()
-			go c.Stop()
 
 		case <-c.stopChan:
 			// When receiving a stop signal we stop the goroutine
@@ -210,6 +345,8 @@ func (c *Classic) Start() {
 func (c *Classic) Stop() {
 	c.playerMux.Lock()
 	if !c.isRunning {
+		// Start may not have run yet, the game then ends as soon as it does
+		c.stopRequested = true
 		c.playerMux.Unlock()
 		return
 	}
@@ -227,52 +364,44 @@ func (c *Classic) Stop() {
 		close(c.stopChan)
 	}
 
+	// The winner or every member of the winning team gets the win bonus. It is
+	// the only thing that counts for the lobby, the points scored during the
+	// game only end up in the history.
 	result := game.GameResult{
-		Winner: "",
-		Scores: make(map[string]int),
+		Winner:      c.sim.Winner(),
+		WinningTeam: c.sim.WinningTeam(),
+		Scores:      make(map[string]int),
+		Points:      c.sim.Scores(),
 	}
-
-	if c.isTimeOut {
-		var healthiestPlayer *Player = nil
-		isUnique := true
-		// The time ran out so we will check if one of the
-		// players has more health left then the others
-		for _, player := range c.players {
-			if healthiestPlayer == nil || player.Health > healthiestPlayer.Health {
-				healthiestPlayer = player
-			} else if player.Health == healthiestPlayer.Health {
-				isUnique = false
-			}
-		}
-
-		if isUnique {
-			result.Winner = healthiestPlayer.ID
-		}
-	} else {
-		// There can only be one winner
-		// The field will be left empty, if
-		// it's a draw
-		for pId, player := range c.players {
-			if player.Health > 0 {
-				if result.Winner != "" {
-					result.Winner = ""
-					break
-				}
-				result.Winner = pId
-			}
-		}
+	awardWin := func(playerID string) {
+		result.Scores[playerID] += c.sim.Config().WinScorePoints
+		result.Points[playerID] += c.sim.Config().WinScorePoints
 	}
-
 	if result.Winner != "" {
-		result.Scores[result.Winner] = WIN_SCORE_POINTS
+		awardWin(result.Winner)
+	}
+	if result.WinningTeam != 0 {
+		for playerID, team := range c.sim.Teams() {
+			if team == result.WinningTeam {
+				awardWin(playerID)
+			}
+		}
 	}
 
-	if c.history != nil {
-		var winnerAuthToken string = ""
-		if player, ok := c.players[result.Winner]; ok {
-			winnerAuthToken = player.AuthToken
+	if history := c.sim.History(); history != nil {
+		scores := make(map[string]int, len(result.Points))
+		for playerID, points := range result.Points {
+			scores[c.sim.HistoryKey(playerID)] = points
+		}
+		gameHistoryForSerialization := history.ToGameHistory(
+			c.sim.AuthToken(result.Winner),
+			result.WinningTeam,
+			scores,
+		)
+		gameHistoryForSerialization.InputStats = make(map[string]InputStats, len(c.inputs.stats))
+		for playerID, stats := range c.inputs.stats {
+			gameHistoryForSerialization.InputStats[c.sim.HistoryKey(playerID)] = *stats
 		}
-		gameHistoryForSerialization := c.history.ToGameHistory(winnerAuthToken)
 		b, err := json.Marshal(gameHistoryForSerialization)
 		if err != nil {
 			log.Error("Failed to marshal game history: %v", err)
@@ -325,16 +454,40 @@ func (c *Classic) HandleMessage(player game.Player, msg message.Message) {
 
 		c.playerMux.Lock()
 		defer c.playerMux.Unlock()
-		pState, ok := c.players[playerID]
-		if ok {
-			pState.HandleInput(payload)
-		} else {
+		if !c.sim.HasPlayer(playerID) {
 			log.Warn(
 				"[Game %s] Received input from player %s who is not in the internal state map.",
 				c.gameID,
 				playerID,
 			)
+			return
+		}
+		// An input for a state that was not sent yet can only come from a broken client
+		if payload.Tick > c.sim.Tick() {
+			c.inputs.futureInputReceived(playerID)
+			log.Warn(
+				"[Game %s] Rejected input of player %s for the future tick %d, current tick is %d.",
+				c.gameID,
+				playerID,
+				payload.Tick,
+				c.sim.Tick(),
+			)
+			return
+		}
+		// An input for an older state arrived too late and would end up in the wrong tick
+		if payload.Tick != 0 && payload.Tick < c.sim.Tick() {
+			c.inputs.staleInputReceived(playerID)
+			log.Warn(
+				"[Game %s] Rejected input of player %s for tick %d, current tick is %d.",
+				c.gameID,
+				playerID,
+				payload.Tick,
+				c.sim.Tick(),
+			)
+			return
 		}
+		c.nextInputs[playerID] = PlayerInput{Move: payload.Move, Bomb: payload.Bomb}
+		c.inputs.inputReceived(playerID)
 	// The message is normally not handled here...
 	// The normal hub will handle this message. But the oneShot hub will
 	// just pass this message down to the game so we can handle it here...
@@ -349,9 +502,7 @@ func (c *Classic) HandleMessage(player game.Player, msg message.Message) {
 		c.playerMux.Lock()
 		defer c.playerMux.Unlock()
 
-		pState, ok := c.players[playerID]
-		if ok {
-			pState.AuthToken = payload.AuthToken
+		if c.sim.SetAuthToken(playerID, payload.AuthToken) {
 			log.Success("Player %s received the auth token %s", playerID, payload.AuthToken)
 		} else {
 			log.Warn(
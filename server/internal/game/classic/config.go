@@ -1,24 +1,271 @@
 package classic
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
 
 const (
+	// --- Game ---
+	MIN_PLAYERS = 2
+	MAX_PLAYERS = 4
+)
+
+// Config contains every tweakable parameter of a classic game.
+// It is sent to the players on game start and stored in the history.
+type Config struct {
 	// --- Field ---
-	field_width    = 11
-	field_height   = 11
-	box_spawn_rate = 0.75
+	FieldWidth   int     `json:"fieldWidth"`
+	FieldHeight  int     `json:"fieldHeight"`
+	BoxSpawnRate float64 `json:"boxSpawnRate"`
+
+	// --- Power-Ups ---
+	PowerUpSpawnRate float64 `json:"powerUpSpawnRate"` // Chance that a destroyed box drops a power-up
 
 	// --- Bombs ---
-	fuse_ticks            = 10
-	bomb_explosion_radius = 3 // center + 2 fields in each direction
+	FuseTicks           int `json:"fuseTicks"`
+	BombExplosionRadius int `json:"bombExplosionRadius"` // center + radius-1 fields in each direction
 
 	// --- Game ---
-	MIN_PLAYERS      = 2
-	MAX_PLAYERS      = 4
-	TICK_RATE        = 200 * time.Millisecond
-	WIN_SCORE_POINTS = 250
-	MAX_GAME_TIME    = 3 * time.Minute
+	TickRateMs     int `json:"tickRateMs"`
+	MaxGameTimeMs  int `json:"maxGameTimeMs"`
+	WinScorePoints int `json:"winScorePoints"`
+
+	// --- Scoring ---
+	BoxScorePoints      int `json:"boxScorePoints"`      // For every box destroyed by a bomb of the player
+	DamageScorePoints   int `json:"damageScorePoints"`   // For every hit on an opponent
+	KillScorePoints     int `json:"killScorePoints"`     // Additionally for the hit that kills an opponent
+	SurvivalScorePoints int `json:"survivalScorePoints"` // For every player alive after SurvivalScoreTicks
+	SurvivalScoreTicks  int `json:"survivalScoreTicks"`  // 0 disables the survival points
+
+	// --- Sudden Death ---
+	SuddenDeathTicks         int `json:"suddenDeathTicks"`         // Tick the walls start closing in, 0 disables the sudden death
+	SuddenDeathIntervalTicks int `json:"suddenDeathIntervalTicks"` // Ticks between two closing walls
+
+	// --- Teams ---
+	Teams        int  `json:"teams"`        // Number of teams, 0 is free for all
+	FriendlyFire bool `json:"friendlyFire"` // Whether explosions hurt teammates
+
+	// --- Fog of War ---
+	ViewRadius int `json:"viewRadius"` // Manhattan distance a player can see, 0 disables the fog of war
+
+	// --- Network ---
+	KeyframeTicks int `json:"keyframeTicks"` // Ticks between two full states for clients receiving deltas, 0 only sends the first one and after dropped states
 
 	// --- Player ---
-	initial_health = 3
-)
+	InitialHealth   int `json:"initialHealth"`
+	InitialMaxBombs int `json:"initialMaxBombs"`
+}
+
+// DefaultConfig returns the configuration of a standard classic game
+func DefaultConfig() Config {
+	return Config{
+		FieldWidth:   11,
+		FieldHeight:  11,
+		BoxSpawnRate: 0.75,
+
+		PowerUpSpawnRate: 0.3,
+
+		FuseTicks:           10,
+		BombExplosionRadius: 3,
+
+		TickRateMs:     200,
+		MaxGameTimeMs:  3 * 60 * 1000,
+		WinScorePoints: 250,
+
+		BoxScorePoints:      10,
+		DamageScorePoints:   25,
+		KillScorePoints:     100,
+		SurvivalScorePoints: 1,
+		SurvivalScoreTicks:  5,
+
+		SuddenDeathTicks:         0, // Disabled unless configured, 600 are 2 minutes at the default tick rate
+		SuddenDeathIntervalTicks: 2,
+
+		Teams:        0,
+		FriendlyFire: false,
+
+		ViewRadius: 0,
+
+		KeyframeTicks: 25, // Every 5 seconds at the default tick rate
+
+		InitialHealth:   3,
+		InitialMaxBombs: 1,
+	}
+}
+
+func (c Config) TickRate() time.Duration {
+	return time.Duration(c.TickRateMs) * time.Millisecond
+}
+
+func (c Config) MaxGameTime() time.Duration {
+	return time.Duration(c.MaxGameTimeMs) * time.Millisecond
+}
+
+// Validate checks that the config describes a playable game on the default field
+func (c Config) Validate() error {
+	if c.FieldWidth < 5 || c.FieldHeight < 5 {
+		return fmt.Errorf("field must be at least 5x5, got %dx%d", c.FieldWidth, c.FieldHeight)
+	}
+	// The labyrinth walls are on even coordinates, with an even size
+	// the spawn points in the corners would end up inside a wall
+	if c.FieldWidth%2 == 0 || c.FieldHeight%2 == 0 {
+		return fmt.Errorf("field width and height must be odd, got %dx%d", c.FieldWidth, c.FieldHeight)
+	}
+	return c.validateRules()
+}
+
+// validateForMap checks the config of a game on a custom map.
+// The map defines the field size so it follows the rules of Map.Validate.
+func (c Config) validateForMap() error {
+	if c.FieldWidth < MIN_MAP_SIZE || c.FieldHeight < MIN_MAP_SIZE {
+		return fmt.Errorf("map must be at least %dx%d, got %dx%d", MIN_MAP_SIZE, MIN_MAP_SIZE, c.FieldWidth, c.FieldHeight)
+	}
+	return c.validateRules()
+}
+
+// validateRules checks everything but the field size
+func (c Config) validateRules() error {
+	if c.BoxSpawnRate < 0 || c.BoxSpawnRate > 1 {
+		return fmt.Errorf("boxSpawnRate must be between 0 and 1, got %f", c.BoxSpawnRate)
+	}
+	if c.PowerUpSpawnRate < 0 || c.PowerUpSpawnRate > 1 {
+		return fmt.Errorf("powerUpSpawnRate must be between 0 and 1, got %f", c.PowerUpSpawnRate)
+	}
+	if c.FuseTicks < 1 {
+		return fmt.Errorf("fuseTicks must be positive, got %d", c.FuseTicks)
+	}
+	if c.BombExplosionRadius < 1 {
+		return fmt.Errorf("bombExplosionRadius must be positive, got %d", c.BombExplosionRadius)
+	}
+	if c.TickRateMs < 1 {
+		return fmt.Errorf("tickRateMs must be positive, got %d", c.TickRateMs)
+	}
+	if c.MaxGameTimeMs < c.TickRateMs {
+		return fmt.Errorf("maxGameTimeMs must be at least one tick of %dms, got %d", c.TickRateMs, c.MaxGameTimeMs)
+	}
+	if c.BoxScorePoints < 0 || c.DamageScorePoints < 0 || c.KillScorePoints < 0 || c.SurvivalScorePoints < 0 {
+		return fmt.Errorf("score points must not be negative")
+	}
+	if c.SurvivalScoreTicks < 0 {
+		return fmt.Errorf("survivalScoreTicks must not be negative, got %d", c.SurvivalScoreTicks)
+	}
+	if c.SuddenDeathTicks < 0 {
+		return fmt.Errorf("suddenDeathTicks must not be negative, got %d", c.SuddenDeathTicks)
+	}
+	if c.SuddenDeathTicks > 0 && c.SuddenDeathIntervalTicks < 1 {
+		return fmt.Errorf("suddenDeathIntervalTicks must be positive, got %d", c.SuddenDeathIntervalTicks)
+	}
+	if c.Teams != 0 && (c.Teams < 2 || c.Teams > MAX_PLAYERS) {
+		return fmt.Errorf("teams must be 0 or between 2 and %d, got %d", MAX_PLAYERS, c.Teams)
+	}
+	if c.ViewRadius < 0 {
+		return fmt.Errorf("viewRadius must not be negative, got %d", c.ViewRadius)
+	}
+	if c.KeyframeTicks < 0 {
+		return fmt.Errorf("keyframeTicks must not be negative, got %d", c.KeyframeTicks)
+	}
+	if c.InitialHealth < 1 {
+		return fmt.Errorf("initialHealth must be positive, got %d", c.InitialHealth)
+	}
+	if c.InitialMaxBombs < 1 {
+		return fmt.Errorf("initialMaxBombs must be positive, got %d", c.InitialMaxBombs)
+	}
+
+	return nil
+}
+
+// LoadConfig builds a config from the defaults, the optional JSON file at path
+// and the BOMBERMAN_* environment variables, in that order of precedence.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("failed to read config file: %w", err)
+		}
+		// Values missing in the file keep their defaults
+		if err := json.Unmarshal(b, &config); err != nil {
+			return config, fmt.Errorf("failed to unmarshal config file: %w", err)
+		}
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return config, err
+	}
+
+	return config, config.Validate()
+}
+
+// applyEnv overrides the config with the values of all set environment variables
+func (c *Config) applyEnv() error {
+	intEnvs := map[string]*int{
+		"BOMBERMAN_FIELD_WIDTH":                 &c.FieldWidth,
+		"BOMBERMAN_FIELD_HEIGHT":                &c.FieldHeight,
+		"BOMBERMAN_FUSE_TICKS":                  &c.FuseTicks,
+		"BOMBERMAN_BOMB_EXPLOSION_RADIUS":       &c.BombExplosionRadius,
+		"BOMBERMAN_TICK_RATE_MS":                &c.TickRateMs,
+		"BOMBERMAN_MAX_GAME_TIME_MS":            &c.MaxGameTimeMs,
+		"BOMBERMAN_WIN_SCORE_POINTS":            &c.WinScorePoints,
+		"BOMBERMAN_BOX_SCORE_POINTS":            &c.BoxScorePoints,
+		"BOMBERMAN_DAMAGE_SCORE_POINTS":         &c.DamageScorePoints,
+		"BOMBERMAN_KILL_SCORE_POINTS":           &c.KillScorePoints,
+		"BOMBERMAN_SURVIVAL_SCORE_POINTS":       &c.SurvivalScorePoints,
+		"BOMBERMAN_SURVIVAL_SCORE_TICKS":        &c.SurvivalScoreTicks,
+		"BOMBERMAN_SUDDEN_DEATH_TICKS":          &c.SuddenDeathTicks,
+		"BOMBERMAN_SUDDEN_DEATH_INTERVAL_TICKS": &c.SuddenDeathIntervalTicks,
+		"BOMBERMAN_TEAMS":                       &c.Teams,
+		"BOMBERMAN_VIEW_RADIUS":                 &c.ViewRadius,
+		"BOMBERMAN_KEYFRAME_TICKS":              &c.KeyframeTicks,
+		"BOMBERMAN_INITIAL_HEALTH":              &c.InitialHealth,
+		"BOMBERMAN_INITIAL_MAX_BOMBS":           &c.InitialMaxBombs,
+	}
+	for name, target := range intEnvs {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		*target = parsed
+	}
+
+	floatEnvs := map[string]*float64{
+		"BOMBERMAN_BOX_SPAWN_RATE":     &c.BoxSpawnRate,
+		"BOMBERMAN_POWERUP_SPAWN_RATE": &c.PowerUpSpawnRate,
+	}
+	for name, target := range floatEnvs {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		*target = parsed
+	}
+
+	boolEnvs := map[string]*bool{
+		"BOMBERMAN_FRIENDLY_FIRE": &c.FriendlyFire,
+	}
+	for name, target := range boolEnvs {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		*target = parsed
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package classic
+
+import (
+	"github.com/N3moAhead/bombahead/server/internal/game"
+	"github.com/N3moAhead/bombahead/server/internal/message"
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+// TileChange is a single tile of the field that changed since the previous state
+type TileChange struct {
+	Pos  types.Vec2 `json:"pos"`
+	Tile Tile       `json:"tile"`
+}
+
+// ClassicStateDeltaPayload contains only the differences between two states.
+// It is sent instead of a full state to clients that support delta updates.
+// Bombs that are neither listed in Bombs nor in RemovedBombs keep their
+// position and their fuse goes down by one.
+type ClassicStateDeltaPayload struct {
+	Tick            int            `json:"tick"`     // Has to be echoed in the input for this state
+	BaseTick        int            `json:"baseTick"` // Tick of the state the delta has to be applied to
+	Tiles           []TileChange   `json:"tiles,omitempty"`
+	Players         []PlayerState  `json:"players,omitempty"`        // Players that changed or came into sight
+	RemovedPlayers  []string       `json:"removedPlayers,omitempty"` // IDs of players that are no longer part of the state
+	Bombs           []BombState    `json:"bombs,omitempty"`          // New bombs and bombs whose fuse did not simply count down
+	RemovedBombs    []types.Vec2   `json:"removedBombs,omitempty"`
+	Explosions      []types.Vec2   `json:"explosions"`         // Explosions only last a tick, so they are always sent in full
+	PowerUps        []PowerUpState `json:"powerUps,omitempty"` // New power-ups
+	RemovedPowerUps []types.Vec2   `json:"removedPowerUps,omitempty"`
+	Events          []GameEvent    `json:"events"`
+	SuddenDeath     bool           `json:"suddenDeath"`
+	NextWall        *types.Vec2    `json:"nextWall,omitempty"`
+}
+
+// diffStates returns the delta which turns prev into next
+func diffStates(prev, next ClassicStatePayload) ClassicStateDeltaPayload {
+	delta := ClassicStateDeltaPayload{
+		Tick:        next.Tick,
+		BaseTick:    prev.Tick,
+		Explosions:  next.Explosions,
+		Events:      next.Events,
+		SuddenDeath: next.SuddenDeath,
+		NextWall:    next.NextWall,
+	}
+
+	for i, tile := range next.Field.Field {
+		if i >= len(prev.Field.Field) || prev.Field.Field[i] != tile {
+			pos := types.NewVec2(i%next.Field.Width, i/next.Field.Width)
+			delta.Tiles = append(delta.Tiles, TileChange{Pos: pos, Tile: tile})
+		}
+	}
+
+	prevPlayers := make(map[string]PlayerState, len(prev.Players))
+	for _, player := range prev.Players {
+		prevPlayers[player.ID] = player
+	}
+	for _, player := range next.Players {
+		if prevPlayer, ok := prevPlayers[player.ID]; !ok || prevPlayer != player {
+			delta.Players = append(delta.Players, player)
+		}
+		delete(prevPlayers, player.ID)
+	}
+	for _, player := range prev.Players {
+		if _, removed := prevPlayers[player.ID]; removed {
+			delta.RemovedPlayers = append(delta.RemovedPlayers, player.ID)
+		}
+	}
+
+	prevBombs := make(map[types.Vec2]BombState, len(prev.Bombs))
+	for _, bomb := range prev.Bombs {
+		prevBombs[bomb.Pos] = bomb
+	}
+	for _, bomb := range next.Bombs {
+		if prevBomb, ok := prevBombs[bomb.Pos]; !ok || prevBomb.Fuse-1 != bomb.Fuse {
+			delta.Bombs = append(delta.Bombs, bomb)
+		}
+		delete(prevBombs, bomb.Pos)
+	}
+	for _, bomb := range prev.Bombs {
+		if _, removed := prevBombs[bomb.Pos]; removed {
+			delta.RemovedBombs = append(delta.RemovedBombs, bomb.Pos)
+		}
+	}
+
+	prevPowerUps := make(map[types.Vec2]PowerUpState, len(prev.PowerUps))
+	for _, powerUp := range prev.PowerUps {
+		prevPowerUps[powerUp.Pos] = powerUp
+	}
+	for _, powerUp := range next.PowerUps {
+		if prevPowerUp, ok := prevPowerUps[powerUp.Pos]; !ok || prevPowerUp != powerUp {
+			delta.PowerUps = append(delta.PowerUps, powerUp)
+		}
+		delete(prevPowerUps, powerUp.Pos)
+	}
+	for _, powerUp := range prev.PowerUps {
+		if _, removed := prevPowerUps[powerUp.Pos]; removed {
+			delta.RemovedPowerUps = append(delta.RemovedPowerUps, powerUp.Pos)
+		}
+	}
+
+	return delta
+}
+
+// stateMessage is a state ready to be sent to a single player
+type stateMessage struct {
+	msgType message.MessageType
+	payload any
+}
+
+// forgetLastState makes the next state of the client a keyframe.
+// Used when a state could not be sent, the client can't apply deltas
+// on top of a state it never received.
+func (c *Classic) forgetLastState(clientID string) {
+	c.playerMux.Lock()
+	defer c.playerMux.Unlock()
+	delete(c.lastStates, clientID)
+}
+
+// stateMessageFor decides whether the player receives the full state or only
+// the changes since the last state it received. Players without the delta
+// capability always get the full state. The others get a keyframe on their
+// first tick and every KeyframeTicks ticks to resync, deltas in between.
+func (c *Classic) stateMessageFor(player game.Player, state ClassicStatePayload) stateMessage {
+	if !player.HasCapability(message.CAPABILITY_DELTA_STATE) {
+		return stateMessage{msgType: message.ClassicState, payload: state}
+	}
+
+	keyframeTicks := c.sim.Config().KeyframeTicks
+	prev, ok := c.lastStates[player.GetID()]
+	c.lastStates[player.GetID()] = state
+	if !ok || (keyframeTicks > 0 && state.Tick%keyframeTicks == 0) {
+		return stateMessage{msgType: message.ClassicState, payload: state}
+	}
+	return stateMessage{msgType: message.ClassicStateDelta, payload: diffStates(prev, state)}
+}
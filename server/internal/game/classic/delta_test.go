@@ -0,0 +1,191 @@
+package classic
+
+import (
+	"encoding/json"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// DELTA_FIXTURE is also read by the tests of the Go SDK, which apply the
+// deltas with their own decoder and compare the result with the states
+const DELTA_FIXTURE = "testdata/deltas.json"
+
+// deltaSequence are the states a player received in a game together with
+// the deltas between them. Deltas[i] turns States[i] into States[i+1].
+type deltaSequence struct {
+	Name   string                     `json:"name"`
+	States []ClassicStatePayload      `json:"states"`
+	Deltas []ClassicStateDeltaPayload `json:"deltas"`
+}
+
+// recordDeltaSequence plays a random game and records the states of the first player
+func recordDeltaSequence(t *testing.T, name string, config Config, seed int64, ticks int) deltaSequence {
+	t.Helper()
+	s := newTestSimulation(t, config, testMap(t), seed, 4)
+	rng := rand.New(rand.NewSource(seed))
+	sequence := deltaSequence{
+		Name:   name,
+		States: []ClassicStatePayload{s.StateFor("player-0")},
+	}
+	for range ticks {
+		if s.IsGameOver() {
+			break
+		}
+		inputs := make(map[string]PlayerInput)
+		for _, player := range s.orderedPlayers() {
+			inputs[player.ID] = PlayerInput{
+				Move: testMoves[rng.Intn(len(testMoves))],
+				Bomb: rng.Intn(3) == 0,
+			}
+		}
+		s.Step(inputs)
+		prev := sequence.States[len(sequence.States)-1]
+		next := s.StateFor("player-0")
+		sequence.States = append(sequence.States, next)
+		sequence.Deltas = append(sequence.Deltas, diffStates(prev, next))
+	}
+	return sequence
+}
+
+func TestDeltaFixture(t *testing.T) {
+	fog := DefaultConfig()
+	fog.ViewRadius = 2
+	sequences := []deltaSequence{
+		recordDeltaSequence(t, "full state", DefaultConfig(), 3, 30),
+		recordDeltaSequence(t, "fog of war", fog, 5, 30),
+	}
+
+	b, err := json.Marshal(sequences)
+	if err != nil {
+		t.Fatalf("marshalling the sequences: %v", err)
+	}
+	b = append(b, '\n')
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(DELTA_FIXTURE), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(DELTA_FIXTURE, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(DELTA_FIXTURE)
+	if err != nil {
+		t.Fatalf("reading %s, run the tests with -update to create it: %v", DELTA_FIXTURE, err)
+	}
+	if string(golden) != string(b) {
+		t.Fatalf("%s is outdated, run the tests with -update to rewrite it", DELTA_FIXTURE)
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	base := func() ClassicStatePayload {
+		return ClassicStatePayload{
+			Tick: 1,
+			Players: []PlayerState{
+				{ID: "a", Pos: types.NewVec2(1, 1), Health: 3},
+				{ID: "b", Pos: types.NewVec2(3, 3), Health: 3},
+			},
+			Field: FieldState{
+				Width:  5,
+				Height: 5,
+				Field:  make([]Tile, 25),
+			},
+			Bombs:      []BombState{{Pos: types.NewVec2(2, 1), Fuse: 5}},
+			Explosions: []types.Vec2{},
+			PowerUps:   []PowerUpState{{Pos: types.NewVec2(3, 1), Type: EXTRA_BOMB}},
+			Events:     []GameEvent{},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(next *ClassicStatePayload)
+		check  func(t *testing.T, delta ClassicStateDeltaPayload)
+	}{
+		{
+			name:   "only the fuse counts down",
+			change: func(next *ClassicStatePayload) {},
+			check: func(t *testing.T, delta ClassicStateDeltaPayload) {
+				if len(delta.Tiles)+len(delta.Players)+len(delta.Bombs)+len(delta.PowerUps) != 0 {
+					t.Errorf("expected an empty delta, got %+v", delta)
+				}
+			},
+		},
+		{
+			name: "tile changed",
+			change: func(next *ClassicStatePayload) {
+				next.Field.Field[3*5+2] = BOX
+			},
+			check: func(t *testing.T, delta ClassicStateDeltaPayload) {
+				want := []TileChange{{Pos: types.NewVec2(2, 3), Tile: BOX}}
+				if len(delta.Tiles) != 1 || delta.Tiles[0] != want[0] {
+					t.Errorf("got tiles %+v, expected %+v", delta.Tiles, want)
+				}
+			},
+		},
+		{
+			name: "player moved and player left",
+			change: func(next *ClassicStatePayload) {
+				next.Players = []PlayerState{{ID: "a", Pos: types.NewVec2(1, 2), Health: 3}}
+			},
+			check: func(t *testing.T, delta ClassicStateDeltaPayload) {
+				if len(delta.Players) != 1 || delta.Players[0].ID != "a" {
+					t.Errorf("got players %+v, expected only a", delta.Players)
+				}
+				if len(delta.RemovedPlayers) != 1 || delta.RemovedPlayers[0] != "b" {
+					t.Errorf("got removed players %v, expected b", delta.RemovedPlayers)
+				}
+			},
+		},
+		{
+			name: "bomb exploded and bomb placed",
+			change: func(next *ClassicStatePayload) {
+				next.Bombs = []BombState{{Pos: types.NewVec2(1, 1), Fuse: 10}}
+			},
+			check: func(t *testing.T, delta ClassicStateDeltaPayload) {
+				if len(delta.Bombs) != 1 || delta.Bombs[0].Pos != types.NewVec2(1, 1) {
+					t.Errorf("got bombs %+v, expected the new one", delta.Bombs)
+				}
+				if len(delta.RemovedBombs) != 1 || delta.RemovedBombs[0] != types.NewVec2(2, 1) {
+					t.Errorf("got removed bombs %v, expected (2,1)", delta.RemovedBombs)
+				}
+			},
+		},
+		{
+			name: "power-up collected",
+			change: func(next *ClassicStatePayload) {
+				next.PowerUps = []PowerUpState{}
+			},
+			check: func(t *testing.T, delta ClassicStateDeltaPayload) {
+				if len(delta.RemovedPowerUps) != 1 || delta.RemovedPowerUps[0] != types.NewVec2(3, 1) {
+					t.Errorf("got removed power-ups %v, expected (3,1)", delta.RemovedPowerUps)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := base()
+			next := base()
+			next.Tick = 2
+			next.Field.Field = append([]Tile{}, prev.Field.Field...)
+			next.Bombs[0].Fuse--
+			tt.change(&next)
+
+			delta := diffStates(prev, next)
+			if delta.BaseTick != prev.Tick || delta.Tick != next.Tick {
+				t.Errorf("delta from tick %d to %d, expected %d to %d", delta.BaseTick, delta.Tick, prev.Tick, next.Tick)
+			}
+			tt.check(t, delta)
+		})
+	}
+}
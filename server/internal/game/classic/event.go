@@ -0,0 +1,37 @@
+package classic
+
+import "github.com/N3moAhead/bombahead/server/pkg/types"
+
+type GameEventType string
+
+const (
+	EVENT_BOMB_PLACED       GameEventType = "bomb_placed"
+	EVENT_BOMB_EXPLODED     GameEventType = "bomb_exploded"
+	EVENT_BOX_DESTROYED     GameEventType = "box_destroyed"
+	EVENT_PLAYER_DAMAGED    GameEventType = "player_damaged"
+	EVENT_PLAYER_ELIMINATED GameEventType = "player_eliminated"
+	EVENT_POWERUP_COLLECTED GameEventType = "powerup_collected"
+)
+
+// GameEvent describes something that happened during a tick.
+// Only the fields relevant for the event type are set.
+type GameEvent struct {
+	Type GameEventType `json:"type"`
+	Pos  types.Vec2    `json:"pos"` // Where the event happened
+
+	// The player placing the bomb, collecting the power-up or being hit
+	PlayerID string `json:"playerId,omitempty"`
+	// The owner of the bomb, for hits and destroyed boxes the player credited for it
+	OwnerID string `json:"ownerId,omitempty"`
+	// Position of the bomb whose explosion triggered this bomb in a chain reaction
+	ChainParent *types.Vec2 `json:"chainParent,omitempty"`
+	// Health left after a hit
+	Health int `json:"health,omitempty"`
+	// The collected power-up
+	PowerUp PowerUpType `json:"powerUp,omitempty"`
+}
+
+// emit records an event for the current tick
+func (s *Simulation) emit(event GameEvent) {
+	s.events = append(s.events, event)
+}
@@ -1,20 +1,50 @@
 package classic
 
-type Field [field_width * field_height]Tile
+import (
+	"math/rand"
 
-func NewField() *Field {
-	f := Field{} // Will be initted with all air
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+type Field struct {
+	width       int
+	height      int
+	tiles       []Tile
+	spawnPoints []types.Vec2 // Spawn points in the order they are assigned to the players
+}
+
+// getSpawnPoints returns the spawn points of the players
+// which are located in the corners of the map
+func getSpawnPoints(width, height int) []types.Vec2 {
+	return []types.Vec2{
+		types.NewVec2(1, 1),              // Top-Left
+		types.NewVec2(width-2, 1),        // Top-Right
+		types.NewVec2(1, height-2),       // Bottom-Left
+		types.NewVec2(width-2, height-2), // Bottom-Right
+	}
+}
+
+// NewField creates the labyrinth and fills it with boxes. The box placement
+// only depends on the given seed so the same seed always results in the same field.
+func NewField(config Config, seed int64) *Field {
+	f := Field{
+		width:       config.FieldWidth,
+		height:      config.FieldHeight,
+		tiles:       make([]Tile, config.FieldWidth*config.FieldHeight),
+		spawnPoints: getSpawnPoints(config.FieldWidth, config.FieldHeight),
+	}
+	rng := rand.New(rand.NewSource(seed))
 
 	// Let's place some walls :)
-	for x := range field_width {
-		for y := range field_height {
+	for x := range f.width {
+		for y := range f.height {
 			f.setTile(x, y, AIR) // Everything is air in the beginning
 			// left or right wall
-			if x == 0 || x == field_width-1 {
+			if x == 0 || x == f.width-1 {
 				f.setTile(x, y, WALL)
 			}
 			// top or bot wall
-			if y == 0 || y == field_height-1 {
+			if y == 0 || y == f.height-1 {
 				f.setTile(x, y, WALL)
 			}
 
@@ -23,19 +53,66 @@ func NewField() *Field {
 				f.setTile(x, y, WALL)
 			}
 
-			// TODO add box placement
+			// Boxes are only placed on air and never next to a spawn
+			// point otherwise players could be boxed in right at the start
+			if f.getTile(x, y) == AIR && !isSpawnArea(f.spawnPoints, x, y) && rng.Float64() < config.BoxSpawnRate {
+				f.setTile(x, y, BOX)
+			}
 		}
 	}
 
 	return &f
 }
 
+// NewFieldFromMap builds the field of a custom map. Random boxes are
+// only placed if the map asks for them and depend on the given seed.
+func NewFieldFromMap(m *Map, config Config, seed int64) *Field {
+	f := Field{
+		width:       m.Width(),
+		height:      m.Height(),
+		tiles:       make([]Tile, m.Width()*m.Height()),
+		spawnPoints: m.spawnPoints(),
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	for x := range f.width {
+		for y := range f.height {
+			switch m.Tiles[y][x] {
+			case MAP_WALL:
+				f.setTile(x, y, WALL)
+			case MAP_BOX:
+				f.setTile(x, y, BOX)
+			default:
+				f.setTile(x, y, AIR)
+			}
+
+			if m.RandomBoxes && f.getTile(x, y) == AIR && !isSpawnArea(f.spawnPoints, x, y) && rng.Float64() < config.BoxSpawnRate {
+				f.setTile(x, y, BOX)
+			}
+		}
+	}
+
+	return &f
+}
+
+// isSpawnArea reports whether the tile is a spawn point or directly next to one
+func isSpawnArea(spawnPoints []types.Vec2, x, y int) bool {
+	for _, spawn := range spawnPoints {
+		dx := spawn.X - x
+		dy := spawn.Y - y
+		if dx*dx+dy*dy <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *Field) getTile(x, y int) Tile {
-	return f[y*field_height+x]
+	return f.tiles[y*f.width+x]
 }
 
 func (f *Field) setTile(x, y int, tile Tile) {
-	f[y*field_height+x] = tile
+	f.tiles[y*f.width+x] = tile
 }
 
 func (f *Field) isTileBlocked(x, y int) bool {
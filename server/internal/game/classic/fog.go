@@ -0,0 +1,100 @@
+package classic
+
+import "github.com/N3moAhead/bombahead/server/pkg/types"
+
+// HasFogOfWar reports whether every player only sees the tiles around them
+func (s *Simulation) HasFogOfWar() bool {
+	return s.config.ViewRadius > 0
+}
+
+// canSee reports whether the tile is within the view radius of the player
+func (s *Simulation) canSee(player *Player, pos types.Vec2) bool {
+	return !s.HasFogOfWar() || player.Pos.ManhattanDist(pos) <= s.config.ViewRadius
+}
+
+// StateFor returns the state as it is seen by the given player. Without fog of war
+// this is the full state. Otherwise everything out of sight is removed and hidden
+// tiles are sent as FOG. The player itself and its teammates are always visible.
+func (s *Simulation) StateFor(playerID string) ClassicStatePayload {
+	state := s.getGameState()
+	viewer, ok := s.players[playerID]
+	if !s.HasFogOfWar() || !ok {
+		return state
+	}
+
+	field := make([]Tile, len(state.Field.Field))
+	for i, tile := range state.Field.Field {
+		pos := types.NewVec2(i%state.Field.Width, i/state.Field.Width)
+		if s.canSee(viewer, pos) {
+			field[i] = tile
+		} else {
+			field[i] = FOG
+		}
+	}
+	state.Field.Field = field
+
+	players := []PlayerState{}
+	for _, player := range state.Players {
+		other := s.players[player.ID]
+		if other == viewer || isSameTeam(other, viewer) || s.canSee(viewer, player.Pos) {
+			players = append(players, player)
+		}
+	}
+	state.Players = players
+
+	bombs := []BombState{}
+	for _, bomb := range state.Bombs {
+		if s.canSee(viewer, bomb.Pos) {
+			bombs = append(bombs, bomb)
+		}
+	}
+	state.Bombs = bombs
+
+	explosions := []types.Vec2{}
+	for _, explosion := range state.Explosions {
+		if s.canSee(viewer, explosion) {
+			explosions = append(explosions, explosion)
+		}
+	}
+	state.Explosions = explosions
+
+	powerUps := []PowerUpState{}
+	for _, powerUp := range state.PowerUps {
+		if s.canSee(viewer, powerUp.Pos) {
+			powerUps = append(powerUps, powerUp)
+		}
+	}
+	state.PowerUps = powerUps
+
+	// Events concerning the player are always sent, even if they happened out of sight
+	events := []GameEvent{}
+	for _, event := range state.Events {
+		if event.PlayerID == playerID || event.OwnerID == playerID || s.canSee(viewer, event.Pos) {
+			events = append(events, event)
+		}
+	}
+	state.Events = events
+
+	return state
+}
+
+// visibleTiles returns the indices (y*width+x) of all tiles every player can see
+// by history key or nil without fog of war
+func (s *Simulation) visibleTiles() map[string][]int {
+	if !s.HasFogOfWar() {
+		return nil
+	}
+	visible := make(map[string][]int, len(s.players))
+	for _, player := range s.players {
+		tiles := []int{}
+		for y := range s.field.height {
+			for x := range s.field.width {
+				if s.canSee(player, types.NewVec2(x, y)) {
+					tiles = append(tiles, y*s.field.width+x)
+				}
+			}
+		}
+		visible[s.HistoryKey(player.ID)] = tiles
+	}
+	return visible
+}
@@ -4,148 +4,263 @@ import (
 	"github.com/N3moAhead/bombahead/server/pkg/types"
 )
 
-func (c *Classic) update() []types.Vec2 {
+// update advances the game by one tick. The steps are always resolved in the same order:
+//  1. Bombs are placed, in spawn order of the players. A player can place
+//     a bomb and move in the same tick, the bomb is placed before the move
+//  2. Players move, all moves are checked against the field after step 1.
+//     Players don't block each other so several players can end up on the same tile.
+//     A player with BOMB_KICK walking into a bomb stays and kicks the bomb instead
+//  3. Kicked bombs slide one tile in the order they were placed in
+//  4. Power-ups are collected, contested power-ups go to the lowest spawn index
+//  5. Bombs tick down and explode in the order they were placed in
+//  6. Destroyed boxes may drop power-ups
+//  7. Players standing in an explosion are damaged
+//  8. During the sudden death the next tile of the spiral becomes a wall
+//  9. Surviving players are awarded their survival points
+//
+// Points for destroyed boxes and hit players go to the owner of the bomb.
+// In a chain reaction the owner of the bomb that started the chain is credited.
+func (s *Simulation) update() []types.Vec2 {
 	// Process player inputs first to ensure their actions are part of this tick's calculations
-	c.applyPlayerInput()
+	s.applyPlayerInput()
+	s.slideBombs()
+	s.collectPowerUps()
 
 	// Gotta clean up the mess from last tick
-	c.resetExplosions()
+	s.resetExplosions()
 
-	destroyedBoxes := c.updateBombs()
+	destroyedBoxes := s.updateBombs()
+	s.dropPowerUps(destroyedBoxes)
 
-	c.damagePlayersInExplosions()
+	s.damagePlayersInExplosions()
+	s.closedWalls = s.closeWalls()
+	s.awardSurvivalScore()
 
 	return destroyedBoxes
 }
 
-func (c *Classic) damagePlayersInExplosions() {
-	for _, player := range c.players {
-		if _, ok := c.explosions[player.Pos.String()]; ok {
-			player.Health -= 1
+func (s *Simulation) damagePlayersInExplosions() {
+	for _, player := range s.orderedPlayers() {
+		if player.Health <= 0 {
+			continue
+		}
+		explosion, ok := s.explosions[player.Pos.String()]
+		if !ok {
+			continue
+		}
+		owner, ownerInGame := s.players[explosion.OwnerID]
+		isTeammate := ownerInGame && owner != player && isSameTeam(owner, player)
+		if isTeammate && !s.config.FriendlyFire {
+			continue
+		}
+		if player.HasShield {
+			// The shield absorbs the hit and is used up
+			player.HasShield = false
+			continue
+		}
+		player.Health -= 1
+		s.emit(GameEvent{
+			Type:     EVENT_PLAYER_DAMAGED,
+			Pos:      player.Pos,
+			PlayerID: player.ID,
+			OwnerID:  explosion.OwnerID,
+			Health:   player.Health,
+		})
+		if player.Health <= 0 {
+			s.emit(GameEvent{
+				Type:     EVENT_PLAYER_ELIMINATED,
+				Pos:      player.Pos,
+				PlayerID: player.ID,
+				OwnerID:  explosion.OwnerID,
+			})
+		}
+
+		// Hitting yourself or a teammate is not worth any points
+		if explosion.OwnerID == player.ID || isTeammate {
+			continue
+		}
+		s.awardScore(explosion.OwnerID, s.config.DamageScorePoints)
+		if player.Health <= 0 {
+			s.awardScore(explosion.OwnerID, s.config.KillScorePoints)
 		}
 	}
 }
 
-func (c *Classic) applyPlayerInput() {
-	for _, player := range c.players {
+func (s *Simulation) applyPlayerInput() {
+	players := s.orderedPlayers()
+
+	// Bombs are placed before anyone moves, so a player can't
+	// walk onto a tile a bomb is placed on in the same tick
+	for _, player := range players {
+		if player.NextMove == PLACE_BOMB || player.NextBomb {
+			s.placeBomb(player)
+		}
+	}
+
+	for _, player := range players {
 		switch player.NextMove {
 		case MOVE_UP:
-			newPos := player.Pos.Add(types.Vec2{X: 0, Y: -1})
-			// TODO Check if the tile contains a bomb the player can't walk over bombs
-			if !c.field.isTileBlocked(newPos.X, newPos.Y) {
-				player.Pos = newPos
-			}
+			s.movePlayer(player, types.NewVec2(0, -1))
 		case MOVE_RIGHT:
-			newPos := player.Pos.Add(types.Vec2{X: 1, Y: 0})
-			if !c.field.isTileBlocked(newPos.X, newPos.Y) {
-				player.Pos = newPos
-			}
+			s.movePlayer(player, types.NewVec2(1, 0))
 		case MOVE_DOWN:
-			newPos := player.Pos.Add(types.Vec2{X: 0, Y: 1})
-			if !c.field.isTileBlocked(newPos.X, newPos.Y) {
-				player.Pos = newPos
-			}
+			s.movePlayer(player, types.NewVec2(0, 1))
 		case MOVE_LEFT:
-			newPos := player.Pos.Add(types.Vec2{X: -1, Y: 0})
-			if !c.field.isTileBlocked(newPos.X, newPos.Y) {
-				player.Pos = newPos
-			}
-		case PLACE_BOMB:
-			if !c.containsBomb(player.Pos) {
-				newBomb := NewBomb(player.Pos)
-				c.bombs[newBomb.Pos.String()] = newBomb
-			}
+			s.movePlayer(player, types.NewVec2(-1, 0))
 		default:
-			// Is the do nothing move
+			// Is the do nothing or place bomb move
 			// or the user has not defined input move
 		}
 	}
 }
 
-func (c *Classic) updateBombs() []types.Vec2 {
-	var allDestroyedBoxes []types.Vec2
-	// Iterate over a copy of keys, as `explodeBomb` can modify c.bombs in a chain reaction.
-	bombKeys := make([]string, 0, len(c.bombs))
-	for k := range c.bombs {
-		bombKeys = append(bombKeys, k)
+// placeBomb places a bomb below the player if the tile is free
+// and the player has not reached the bomb limit yet
+func (s *Simulation) placeBomb(player *Player) {
+	if s.containsBomb(player.Pos) || s.activeBombCount(player.ID) >= player.MaxBombs {
+		return
 	}
+	s.bombCount += 1
+	newBomb := NewBomb(player.Pos, player.ID, s.config.FuseTicks, player.BlastRadius, s.bombCount)
+	s.bombs[newBomb.Pos.String()] = newBomb
+	s.emit(GameEvent{
+		Type:     EVENT_BOMB_PLACED,
+		Pos:      newBomb.Pos,
+		PlayerID: player.ID,
+		OwnerID:  player.ID,
+	})
+}
+
+// movePlayer moves the player one tile into the given direction
+// if the target tile can be walked on or kicks the bomb on it
+func (s *Simulation) movePlayer(player *Player, dir types.Vec2) {
+	newPos := player.Pos.Add(dir)
+	if bomb, ok := s.bombs[newPos.String()]; ok && player.CanKickBombs {
+		bomb.kick(dir)
+		return
+	}
+	if s.canWalkOn(player, newPos) {
+		player.Pos = newPos
+	}
+}
+
+// canWalkOn checks if the player is allowed to enter the given tile.
+// Walls and boxes are always blocked. Tiles with a bomb are blocked as well,
+// only a player still standing on a bomb (e.g. right after placing it) may stay on it
+// and players with the BOMB_PASS power-up may walk over bombs.
+func (s *Simulation) canWalkOn(player *Player, pos types.Vec2) bool {
+	if s.field.isTileBlocked(pos.X, pos.Y) {
+		return false
+	}
+	if s.containsBomb(pos) && player.Pos != pos && !player.CanPassBombs {
+		return false
+	}
+	return true
+}
 
-	for _, key := range bombKeys {
-		bomb, exists := c.bombs[key]
-		if !exists { // It might have been destroyed by another bomb in the same tick
+func (s *Simulation) updateBombs() []types.Vec2 {
+	var allDestroyedBoxes []types.Vec2
+	// Iterate over an ordered copy, as `explodeBomb` can modify s.bombs in a chain reaction.
+	for _, bomb := range s.orderedBombs() {
+		if _, exists := s.bombs[bomb.Pos.String()]; !exists { // It might have been destroyed by another bomb in the same tick
 			continue
 		}
 
 		bomb.Fuse -= 1
 		if bomb.Fuse < 1 {
-			delete(c.bombs, bomb.Pos.String())
-			destroyedBoxes := c.explodeBomb(bomb.Pos, bomb_explosion_radius)
+			delete(s.bombs, bomb.Pos.String())
+			destroyedBoxes := s.explodeBomb(bomb, bomb.OwnerID, nil)
 			allDestroyedBoxes = append(allDestroyedBoxes, destroyedBoxes...)
 		}
 	}
 	return allDestroyedBoxes
 }
 
-func (c *Classic) resetExplosions() {
-	for k := range c.explosions {
-		delete(c.explosions, k)
+func (s *Simulation) resetExplosions() {
+	for k := range s.explosions {
+		delete(s.explosions, k)
 	}
 }
 
-func (c *Classic) explodeBomb(pos types.Vec2, distance int) []types.Vec2 {
+// explodeBomb spreads the explosion of the bomb into all four directions.
+// ownerID is the player credited for everything the explosion hits and
+// chainParent the position of the bomb that triggered it, if any.
+func (s *Simulation) explodeBomb(bomb *Bomb, ownerID string, chainParent *types.Vec2) []types.Vec2 {
+	s.emit(GameEvent{
+		Type:        EVENT_BOMB_EXPLODED,
+		Pos:         bomb.Pos,
+		OwnerID:     bomb.OwnerID,
+		ChainParent: chainParent,
+	})
+
+	pos := bomb.Pos
+	distance := bomb.Radius
 	var destroyedBoxes []types.Vec2
 	// Up
-	destroyedBoxes = append(destroyedBoxes, c.createExplodePath(pos, types.NewVec2(0, -1), distance)...)
+	destroyedBoxes = append(destroyedBoxes, s.createExplodePath(pos, types.NewVec2(0, -1), distance, bomb, ownerID)...)
 	// Right
-	destroyedBoxes = append(destroyedBoxes, c.createExplodePath(pos, types.NewVec2(1, 0), distance)...)
+	destroyedBoxes = append(destroyedBoxes, s.createExplodePath(pos, types.NewVec2(1, 0), distance, bomb, ownerID)...)
 	// Down
-	destroyedBoxes = append(destroyedBoxes, c.createExplodePath(pos, types.NewVec2(0, 1), distance)...)
+	destroyedBoxes = append(destroyedBoxes, s.createExplodePath(pos, types.NewVec2(0, 1), distance, bomb, ownerID)...)
 	// Left
-	destroyedBoxes = append(destroyedBoxes, c.createExplodePath(pos, types.NewVec2(-1, 0), distance)...)
+	destroyedBoxes = append(destroyedBoxes, s.createExplodePath(pos, types.NewVec2(-1, 0), distance, bomb, ownerID)...)
 	return destroyedBoxes
 }
 
-func (c *Classic) createExplodePath(pos types.Vec2, dir types.Vec2, distance int) []types.Vec2 {
+func (s *Simulation) createExplodePath(pos types.Vec2, dir types.Vec2, distance int, source *Bomb, ownerID string) []types.Vec2 {
 	// I won't check if the explosion is out of bounds because a bomb can't be placed
 	// out of bounds so it could not occur...
 	if distance == 0 {
 		return nil
 	}
-	tile := c.field.getTile(pos.X, pos.Y)
+	tile := s.field.getTile(pos.X, pos.Y)
 	if tile == WALL {
 		return nil
 	}
 	if tile == BOX {
-		c.field.setTile(pos.X, pos.Y, AIR)
-		c.addExplosion(pos)
+		s.field.setTile(pos.X, pos.Y, AIR)
+		s.addExplosion(pos, ownerID)
+		s.awardScore(ownerID, s.config.BoxScorePoints)
+		s.emit(GameEvent{
+			Type:    EVENT_BOX_DESTROYED,
+			Pos:     pos,
+			OwnerID: ownerID,
+		})
 		return []types.Vec2{pos}
 	}
 	if tile == AIR {
 		var destroyedBoxes []types.Vec2
 		// Check if the current tile contains a bomb to trigger a chain reaction
-		if c.containsBomb(pos) {
+		if chainedBomb, ok := s.bombs[pos.String()]; ok {
 			// It is important to delete the bomb before calling `explodeBomb` to prevent infinite recursion
-			delete(c.bombs, pos.String())
-			// This explosion triggers another bomb
-			destroyedBoxes = c.explodeBomb(pos, bomb_explosion_radius)
+			delete(s.bombs, pos.String())
+			// This explosion triggers another bomb, which is credited to the same owner
+			chainParent := source.Pos
+			destroyedBoxes = s.explodeBomb(chainedBomb, ownerID, &chainParent)
 		}
-		c.addExplosion(pos)
+		s.addExplosion(pos, ownerID)
 		// Continue the explosion path
-		recursiveDestroyedBoxes := c.createExplodePath(pos.Add(dir), dir, distance-1)
+		recursiveDestroyedBoxes := s.createExplodePath(pos.Add(dir), dir, distance-1, source, ownerID)
 		return append(destroyedBoxes, recursiveDestroyedBoxes...)
 	}
 	return nil
 }
 
-func (c *Classic) addExplosion(pos types.Vec2) {
-	if _, ok := c.explosions[pos.String()]; !ok {
-		c.explosions[pos.String()] = pos
+// addExplosion marks the tile as exploding. If several explosions overlap
+// the first one, i.e. the earliest placed bomb, keeps the credit.
+func (s *Simulation) addExplosion(pos types.Vec2, ownerID string) {
+	if _, ok := s.explosions[pos.String()]; !ok {
+		s.explosions[pos.String()] = Explosion{Pos: pos, OwnerID: ownerID}
 	}
 }
 
-func (c *Classic) isGameOver() bool {
+func (s *Simulation) isGameOver() bool {
+	if s.config.Teams > 0 {
+		return len(s.livingTeams()) <= 1
+	}
+
 	alivePlayers := 0
-	for _, player := range c.players {
+	for _, player := range s.players {
 		if player.Health > 0 {
 			alivePlayers += 1
 		}
@@ -156,52 +271,59 @@ func (c *Classic) isGameOver() bool {
 	return alivePlayers <= 1
 }
 
-func (c *Classic) getGameState() ClassicStatePayload {
+func (s *Simulation) getGameState() ClassicStatePayload {
 	// Get Players
 	pStates := []PlayerState{}
-	for _, player := range c.players {
-		pState := PlayerState{
-			ID:     player.ID,
-			Pos:    player.Pos,
-			Health: player.Health,
-			Score:  player.Score,
-		}
-		pStates = append(pStates, pState)
+	for _, player := range s.orderedPlayers() {
+		pStates = append(pStates, player.getState())
 	}
 
 	// Get Field
+	// The field is sent row by row so clients can index it with y*width+x
 	field := []Tile{}
-	for x := range field_width {
-		for y := range field_height {
-			field = append(field, c.field.getTile(x, y))
+	for y := range s.field.height {
+		for x := range s.field.width {
+			field = append(field, s.field.getTile(x, y))
 		}
 	}
 	fieldState := FieldState{
-		Width:  field_width,
-		Height: field_height,
+		Width:  s.field.width,
+		Height: s.field.height,
 		Field:  field,
 	}
 	// Get Bombs
 	bombs := []BombState{}
-	for _, bomb := range c.bombs {
+	for _, bomb := range s.orderedBombs() {
 		bombs = append(bombs, BombState{Pos: bomb.Pos, Fuse: bomb.Fuse})
 	}
 	// Get Explosions
-	explosions := []types.Vec2{}
-	for _, ePos := range c.explosions {
-		explosions = append(explosions, ePos)
+	explosions := s.orderedExplosions()
+
+	// Get Power-Ups
+	powerUps := []PowerUpState{}
+	for _, powerUp := range s.orderedPowerUps() {
+		powerUps = append(powerUps, PowerUpState{Pos: powerUp.Pos, Type: powerUp.Type})
 	}
 
+	// Get Events
+	events := append([]GameEvent{}, s.events...)
+
 	return ClassicStatePayload{
-		Players:    pStates,
-		Field:      fieldState,
-		Bombs:      bombs,
-		Explosions: explosions,
+		Tick:        s.tick,
+		Players:     pStates,
+		Field:       fieldState,
+		Bombs:       bombs,
+		Explosions:  explosions,
+		PowerUps:    powerUps,
+		Events:      events,
+		SuddenDeath: s.isSuddenDeath(),
+		NextWall:    s.nextWall(),
 	}
 }
 
-func (c *Classic) resetPlayerInputs() {
-	for _, player := range c.players {
+func (s *Simulation) resetPlayerInputs() {
+	for _, player := range s.players {
 		player.NextMove = NO_INPUT_DEFINED
+		player.NextBomb = false
 	}
 }
@@ -4,36 +4,46 @@ import "github.com/N3moAhead/bombahead/server/pkg/types"
 
 // History manages the recording of a game's progression
 type History struct {
-	InitialField FieldState
-	Ticks        []TickState
+	Seed           int64
+	Config         Config
+	MapName        string
+	InitialField   FieldState
+	InitialPlayers []PlayerState
+	Ticks          []TickState
 }
 
 // NewHistory creates a new game history recorder, capturing the initial state of the field
-func NewHistory(initialField FieldState) *History {
+// and the players together with the seed, the config and the map it was generated from
+func NewHistory(seed int64, config Config, mapName string, initialField FieldState, initialPlayers []PlayerState) *History {
 	return &History{
-		InitialField: initialField,
-		Ticks:        make([]TickState, 0),
+		Seed:           seed,
+		Config:         config,
+		MapName:        mapName,
+		InitialField:   initialField,
+		InitialPlayers: initialPlayers,
+		Ticks:          make([]TickState, 0),
 	}
 }
 
-// RecordTick captures the dynamic state of the game for the current tick
+// RecordTick captures the dynamic state of the game for the current tick.
+// The slices are expected in the deterministic order of the game.
 func (h *History) RecordTick(
-	players map[string]*Player,
-	bombs map[string]*Bomb,
-	explosions map[string]types.Vec2,
+	players []*Player,
+	bombs []*Bomb,
+	explosions []types.Vec2,
+	powerUps []*PowerUp,
 	destroyedBoxes []types.Vec2,
+	closedWalls []types.Vec2,
+	events []GameEvent,
+	visible map[string][]int,
 ) {
 	playerHistory := make([]PlayerHistoryEntry, 0, len(players))
 	for _, p := range players {
 		playerHistory = append(playerHistory, PlayerHistoryEntry{
-			PlayerState: PlayerState{
-				ID:     p.ID,
-				Pos:    p.Pos,
-				Health: p.Health,
-				Score:  p.Score,
-			},
-			Move:      p.NextMove,
-			AuthToken: p.AuthToken,
+			PlayerState: p.getState(),
+			Move:        p.NextMove,
+			Bomb:        p.NextBomb,
+			AuthToken:   p.AuthToken,
 		})
 	}
 
@@ -42,26 +52,45 @@ func (h *History) RecordTick(
 		bombStates = append(bombStates, BombState{Pos: b.Pos, Fuse: b.Fuse})
 	}
 
-	explosionVecs := make([]types.Vec2, 0, len(explosions))
-	for _, e := range explosions {
-		explosionVecs = append(explosionVecs, e)
+	powerUpStates := make([]PowerUpState, 0, len(powerUps))
+	for _, pu := range powerUps {
+		powerUpStates = append(powerUpStates, PowerUpState{Pos: pu.Pos, Type: pu.Type})
 	}
 
 	tick := TickState{
 		Players:        playerHistory,
 		Bombs:          bombStates,
-		Explosions:     explosionVecs,
+		Explosions:     explosions,
+		PowerUps:       powerUpStates,
 		DestroyedBoxes: destroyedBoxes,
+		ClosedWalls:    closedWalls,
+		Events:         events,
+		Visible:        visible,
 	}
 
 	h.Ticks = append(h.Ticks, tick)
 }
 
-// ToGameHistory converts the internal history representation to the serializable format
-func (h *History) ToGameHistory(winnerAuthToken string) GameHistory {
+// RecordLatencies adds the response times of the players to the last recorded tick
+func (h *History) RecordLatencies(latencies map[string]int) {
+	if len(h.Ticks) == 0 || len(latencies) == 0 {
+		return
+	}
+	h.Ticks[len(h.Ticks)-1].Latencies = latencies
+}
+
+// ToGameHistory converts the internal history representation to the serializable format.
+// scores contains the final score of every player by auth token.
+func (h *History) ToGameHistory(winnerAuthToken string, winningTeam int, scores map[string]int) GameHistory {
 	return GameHistory{
+		Seed:            h.Seed,
+		Config:          h.Config,
+		MapName:         h.MapName,
 		InitialField:    h.InitialField,
+		InitialPlayers:  h.InitialPlayers,
 		Ticks:           h.Ticks,
 		WinnerAuthToken: winnerAuthToken,
+		WinningTeam:     winningTeam,
+		Scores:          scores,
 	}
 }
@@ -0,0 +1,74 @@
+package classic
+
+import "time"
+
+// inputTracker keeps track of how timely the players send their inputs.
+// It only measures wall clock time and never influences the simulation.
+type inputTracker struct {
+	stateSentAt time.Time                // When the last state was sent to the players
+	latencies   map[string]time.Duration // PlayerID -> Response time for the last state
+	stats       map[string]*InputStats   // PlayerID -> Stats of the whole game
+	latencySums map[string]time.Duration // PlayerID -> Sum of all response times
+}
+
+func newInputTracker() *inputTracker {
+	return &inputTracker{
+		latencies:   make(map[string]time.Duration),
+		stats:       make(map[string]*InputStats),
+		latencySums: make(map[string]time.Duration),
+	}
+}
+
+func (t *inputTracker) statsOf(playerID string) *InputStats {
+	stats, ok := t.stats[playerID]
+	if !ok {
+		stats = &InputStats{}
+		t.stats[playerID] = stats
+	}
+	return stats
+}
+
+// stateSent marks the moment the players received the state they have to answer
+func (t *inputTracker) stateSent() {
+	t.stateSentAt = time.Now()
+	t.latencies = make(map[string]time.Duration)
+}
+
+// inputReceived records the response time of the first input of a player for the current state
+func (t *inputTracker) inputReceived(playerID string) {
+	if _, ok := t.latencies[playerID]; ok {
+		return
+	}
+	latency := time.Since(t.stateSentAt)
+	t.latencies[playerID] = latency
+	t.latencySums[playerID] += latency
+
+	stats := t.statsOf(playerID)
+	stats.Inputs += 1
+	stats.MaxLatencyMs = max(stats.MaxLatencyMs, int(latency.Milliseconds()))
+	stats.AvgLatencyMs = int((t.latencySums[playerID] / time.Duration(stats.Inputs)).Milliseconds())
+}
+
+// staleInputReceived records an input that was meant for an earlier tick
+func (t *inputTracker) staleInputReceived(playerID string) {
+	t.statsOf(playerID).StaleInputs += 1
+}
+
+// futureInputReceived records an input that was meant for a tick which did not start yet
+func (t *inputTracker) futureInputReceived(playerID string) {
+	t.statsOf(playerID).FutureInputs += 1
+}
+
+// tickMissed records that a player sent no input for the last state
+func (t *inputTracker) tickMissed(playerID string) {
+	t.statsOf(playerID).MissedTicks += 1
+}
+
+// latenciesMs returns the response times for the last state in milliseconds by player ID
+func (t *inputTracker) latenciesMs() map[string]int {
+	latencies := make(map[string]int, len(t.latencies))
+	for playerID, latency := range t.latencies {
+		latencies[playerID] = int(latency.Milliseconds())
+	}
+	return latencies
+}
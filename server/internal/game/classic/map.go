@@ -0,0 +1,164 @@
+package classic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+const DEFAULT_MAP_NAME = "default"
+
+// MIN_MAP_SIZE is the smallest width and height of a map, a single tile surrounded by walls
+const MIN_MAP_SIZE = 3
+
+// Characters used in the tile rows of a map file
+const (
+	MAP_WALL  = '#'
+	MAP_AIR   = '.'
+	MAP_BOX   = 'B'
+	MAP_SPAWN = 'S' // An air tile players can spawn on
+)
+
+// Map describes a custom arena which is loaded from a JSON file like:
+//
+//	{
+//	  "name": "Open Field",
+//	  "randomBoxes": false,
+//	  "tiles": [
+//	    "#######",
+//	    "#S...S#",
+//	    "#..B..#",
+//	    "#S...S#",
+//	    "#######"
+//	  ]
+//	}
+//
+// Spawn points are assigned to the players in reading order.
+// If randomBoxes is set the free tiles are additionally filled with boxes
+// just like on the default field.
+type Map struct {
+	Name        string   `json:"name"`
+	RandomBoxes bool     `json:"randomBoxes"`
+	Tiles       []string `json:"tiles"`
+}
+
+// LoadMap reads and validates the map file at the given path
+func LoadMap(path string) (*Map, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map file: %w", err)
+	}
+
+	var m Map
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal map file: %w", err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid map '%s': %w", path, err)
+	}
+	return &m, nil
+}
+
+func (m *Map) Width() int {
+	if len(m.Tiles) == 0 {
+		return 0
+	}
+	return len(m.Tiles[0])
+}
+
+func (m *Map) Height() int {
+	return len(m.Tiles)
+}
+
+// Validate checks that the map is rectangular, enclosed by walls
+// and that every spawn point can be reached from every other one
+func (m *Map) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("map has no name")
+	}
+	if m.Height() < MIN_MAP_SIZE || m.Width() < MIN_MAP_SIZE {
+		return fmt.Errorf("map must be at least %dx%d, got %dx%d", MIN_MAP_SIZE, MIN_MAP_SIZE, m.Width(), m.Height())
+	}
+
+	for y, row := range m.Tiles {
+		if len(row) != m.Width() {
+			return fmt.Errorf("row %d has %d tiles, expected %d", y, len(row), m.Width())
+		}
+		for x := range len(row) {
+			switch row[x] {
+			case MAP_WALL, MAP_AIR, MAP_BOX, MAP_SPAWN:
+			default:
+				return fmt.Errorf("unknown tile '%c' at (%d,%d)", row[x], x, y)
+			}
+			isBorder := x == 0 || y == 0 || x == m.Width()-1 || y == m.Height()-1
+			if isBorder && row[x] != MAP_WALL {
+				return fmt.Errorf("map is not enclosed, tile (%d,%d) on the border is not a wall", x, y)
+			}
+		}
+	}
+
+	spawnPoints := m.spawnPoints()
+	if len(spawnPoints) < MIN_PLAYERS || len(spawnPoints) > MAX_PLAYERS {
+		return fmt.Errorf(
+			"map must have between %d and %d spawn points, got %d",
+			MIN_PLAYERS,
+			MAX_PLAYERS,
+			len(spawnPoints),
+		)
+	}
+
+	// Boxes can be destroyed so only walls separate the spawn points
+	reachable := m.reachableFrom(spawnPoints[0])
+	for _, spawn := range spawnPoints[1:] {
+		if !reachable[spawn] {
+			return fmt.Errorf("spawn point (%d,%d) can't be reached from (%d,%d)", spawn.X, spawn.Y, spawnPoints[0].X, spawnPoints[0].Y)
+		}
+	}
+
+	return nil
+}
+
+// spawnPoints returns all spawn points in reading order
+func (m *Map) spawnPoints() []types.Vec2 {
+	spawnPoints := []types.Vec2{}
+	for y, row := range m.Tiles {
+		for x := range len(row) {
+			if row[x] == MAP_SPAWN {
+				spawnPoints = append(spawnPoints, types.NewVec2(x, y))
+			}
+		}
+	}
+	return spawnPoints
+}
+
+// reachableFrom returns every tile that is connected to start without crossing a wall
+func (m *Map) reachableFrom(start types.Vec2) map[types.Vec2]bool {
+	directions := []types.Vec2{
+		types.NewVec2(0, -1),
+		types.NewVec2(1, 0),
+		types.NewVec2(0, 1),
+		types.NewVec2(-1, 0),
+	}
+
+	visited := map[types.Vec2]bool{start: true}
+	queue := []types.Vec2{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dir := range directions {
+			next := current.Add(dir)
+			if next.X < 0 || next.Y < 0 || next.X >= m.Width() || next.Y >= m.Height() {
+				continue
+			}
+			if visited[next] || m.Tiles[next.Y][next.X] == MAP_WALL {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+		}
+	}
+	return visited
+}
@@ -0,0 +1,117 @@
+package classic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tiles   []string
+		wantErr string // Part of the expected error, empty if the map is valid
+	}{
+		{
+			name:  "smallest map",
+			tiles: []string{"###", "#S#", "#S#", "###"},
+		},
+		{
+			name:  "spawn points separated by a box",
+			tiles: []string{"#####", "#SBS#", "#####"},
+		},
+		{
+			name:  "four spawn points",
+			tiles: []string{"#####", "#S.S#", "#...#", "#S.S#", "#####"},
+		},
+		{
+			name:    "empty",
+			tiles:   []string{},
+			wantErr: "at least",
+		},
+		{
+			name:    "too narrow",
+			tiles:   []string{"##", "#S", "#S", "##"},
+			wantErr: "at least",
+		},
+		{
+			name:    "too low",
+			tiles:   []string{"#####", "#S.S#"},
+			wantErr: "at least",
+		},
+		{
+			name:    "ragged row",
+			tiles:   []string{"#####", "#S.S#", "#..#", "#####"},
+			wantErr: "row 2 has 4 tiles",
+		},
+		{
+			name:    "unknown tile",
+			tiles:   []string{"#####", "#SxS#", "#####"},
+			wantErr: "unknown tile 'x' at (2,1)",
+		},
+		{
+			name:    "open border",
+			tiles:   []string{"#####", "#S.S.", "#####"},
+			wantErr: "not enclosed",
+		},
+		{
+			name:    "spawn point on the border",
+			tiles:   []string{"##S##", "#S.S#", "#####"},
+			wantErr: "not enclosed",
+		},
+		{
+			name:    "single spawn point",
+			tiles:   []string{"#####", "#S..#", "#####"},
+			wantErr: "got 1",
+		},
+		{
+			name:    "too many spawn points",
+			tiles:   []string{"#######", "#SSSSS#", "#######"},
+			wantErr: "got 5",
+		},
+		{
+			name:    "spawn point behind a wall",
+			tiles:   []string{"#####", "#S#S#", "#####"},
+			wantErr: "can't be reached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arena := &Map{Name: "Test", Tiles: tt.tiles}
+			err := arena.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapValidateRequiresName(t *testing.T) {
+	arena := &Map{Tiles: []string{"#####", "#S.S#", "#####"}}
+	if err := arena.Validate(); err == nil {
+		t.Fatal("expected an error for a map without a name")
+	}
+}
+
+func TestNewSimulationWithSmallestMap(t *testing.T) {
+	arena := &Map{Name: "Test", Tiles: []string{"###", "#S#", "#S#", "###"}}
+	if err := arena.Validate(); err != nil {
+		t.Fatalf("map is invalid: %v", err)
+	}
+	s := NewSimulation(DefaultConfig(), arena, 1)
+	if err := s.Config().validateForMap(); err != nil {
+		t.Fatalf("config of a valid map is rejected: %v", err)
+	}
+	if got := s.MaxPlayers(); got != 2 {
+		t.Fatalf("map allows %d players, expected 2", got)
+	}
+}
@@ -14,20 +14,41 @@ const (
 	PLACE_BOMB       PlayerMove = "place_bomb"
 )
 
+// PlayerInput is everything a player does in a single tick.
+// A bomb is always placed before the player moves, so a bot can
+// drop a bomb and step away from it in the same tick.
+type PlayerInput struct {
+	Move PlayerMove
+	Bomb bool
+}
+
 type ClassicInputPayload struct {
 	Move PlayerMove `json:"move"`
+	// Places a bomb on the current tile before moving. Older bots
+	// only send the PLACE_BOMB move which has the same effect.
+	Bomb bool `json:"bomb,omitempty"`
+	// Tick of the state the input answers. Inputs for older ticks are rejected,
+	// inputs without a tick are accepted for compatibility with older bots.
+	Tick int `json:"tick,omitempty"`
 }
 
 type PlayerState struct {
-	ID     string     `json:"id"`
-	Pos    types.Vec2 `json:"pos"`
-	Health int        `json:"health"`
-	Score  int        `json:"score"`
+	ID           string     `json:"id"`
+	Pos          types.Vec2 `json:"pos"`
+	Health       int        `json:"health"`
+	Score        int        `json:"score"`
+	MaxBombs     int        `json:"maxBombs"`
+	BlastRadius  int        `json:"blastRadius"`
+	CanPassBombs bool       `json:"canPassBombs"`
+	CanKickBombs bool       `json:"canKickBombs"`
+	HasShield    bool       `json:"hasShield"`
+	Team         int        `json:"team,omitempty"` // Team of the player starting at 1, not set in free for all games
 }
 
 type PlayerHistoryEntry struct {
 	PlayerState
 	Move      PlayerMove `json:"move"`
+	Bomb      bool       `json:"bomb,omitempty"` // Whether a bomb was placed in addition to the move
 	AuthToken string     `json:"authToken"`
 }
 
@@ -42,11 +63,21 @@ type BombState struct {
 	Fuse int        `json:"fuse"`
 }
 
+type PowerUpState struct {
+	Pos  types.Vec2  `json:"pos"`
+	Type PowerUpType `json:"type"`
+}
+
 type ClassicStatePayload struct {
-	Players    []PlayerState `json:"players"`
-	Field      FieldState    `json:"field"`
-	Bombs      []BombState   `json:"bombs"`
-	Explosions []types.Vec2  `json:"explosions"`
+	Tick        int            `json:"tick"` // Has to be echoed in the input for this state
+	Players     []PlayerState  `json:"players"`
+	Field       FieldState     `json:"field"`
+	Bombs       []BombState    `json:"bombs"`
+	Explosions  []types.Vec2   `json:"explosions"`
+	PowerUps    []PowerUpState `json:"powerUps"`
+	Events      []GameEvent    `json:"events"`             // Everything that happened in the last tick
+	SuddenDeath bool           `json:"suddenDeath"`        // True while the walls are closing in
+	NextWall    *types.Vec2    `json:"nextWall,omitempty"` // The tile which becomes a wall next during the sudden death
 }
 
 // TickState represents the dynamic state of the game at a single tick for history purposes
@@ -54,13 +85,35 @@ type TickState struct {
 	Players        []PlayerHistoryEntry `json:"players"`
 	Bombs          []BombState          `json:"bombs"`
 	Explosions     []types.Vec2         `json:"explosions"`
+	PowerUps       []PowerUpState       `json:"power_ups"`
 	DestroyedBoxes []types.Vec2         `json:"destroyed_boxes,omitempty"`
+	ClosedWalls    []types.Vec2         `json:"closed_walls,omitempty"` // Tiles that became walls in the sudden death
+	Events         []GameEvent          `json:"events,omitempty"`
+	Visible        map[string][]int     `json:"visible,omitempty"`      // AuthToken (player ID without one) -> indices (y*width+x) of the tiles the player saw, only in fog of war games
+	Latencies      map[string]int       `json:"latencies_ms,omitempty"` // AuthToken (player ID without one) -> Time the player needed to answer the previous state
+}
+
+// InputStats summarizes how timely a player sent its inputs
+type InputStats struct {
+	Inputs       int `json:"inputs"`       // Inputs received in time
+	MissedTicks  int `json:"missedTicks"`  // Ticks without an input
+	StaleInputs  int `json:"staleInputs"`  // Inputs rejected because they answered an earlier state
+	FutureInputs int `json:"futureInputs"` // Inputs rejected because they named a tick that did not start yet
+	AvgLatencyMs int `json:"avgLatencyMs"` // Average time to answer a state
+	MaxLatencyMs int `json:"maxLatencyMs"` // Longest time to answer a state
 }
 
 // GameHistory encapsulates the entire history of a game, with an initial field state
 // and a sequence of state changes for each tick
 type GameHistory struct {
-	InitialField    FieldState  `json:"initial_field"`
-	Ticks           []TickState `json:"ticks"`
-	WinnerAuthToken string      `json:"winnerAuthToken"`
+	Seed            int64                 `json:"seed"` // Seed used to generate the initial field
+	Config          Config                `json:"config"`
+	MapName         string                `json:"map_name"`
+	InitialField    FieldState            `json:"initial_field"`
+	InitialPlayers  []PlayerState         `json:"initial_players"` // Players in spawn order at the start of the game
+	Ticks           []TickState           `json:"ticks"`
+	WinnerAuthToken string                `json:"winnerAuthToken"`
+	WinningTeam     int                   `json:"winningTeam,omitempty"` // Only set in team games
+	Scores          map[string]int        `json:"scores"`                // Final score including the win bonus by auth token
+	InputStats      map[string]InputStats `json:"input_stats,omitempty"` // Timing of the inputs by auth token, player ID without one
 }
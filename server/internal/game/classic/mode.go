@@ -0,0 +1,81 @@
+package classic
+
+import (
+	"fmt"
+
+	"github.com/N3moAhead/bombahead/server/internal/game"
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+const (
+	MODE_NAME      = "classic"
+	TEAM_MODE_NAME = "classic-teams"
+	FOG_MODE_NAME  = "classic-fog"
+)
+
+// NewMode returns the classic game mode, every game of it is played with the given
+// config on the given map. A nil map results in the default labyrinth.
+func NewMode(config Config, arena *Map) game.Mode {
+	return game.Mode{
+		Name:        MODE_NAME,
+		Description: "The classic and simple bomberman game!",
+		MatchSize:   MIN_PLAYERS,
+		MinPlayers:  MIN_PLAYERS,
+		New: func(finisher game.GameFinisher, id string, historyFilePath string) game.Game {
+			return NewClassic(finisher, id, historyFilePath, config, arena)
+		},
+	}
+}
+
+// CheckSpawnPoints returns an error if the map has fewer spawn points than
+// a match of the mode has players or the config has teams. A nil map is the
+// default labyrinth with a spawn point in every corner.
+func CheckSpawnPoints(mode game.Mode, config Config, arena *Map) error {
+	spawnPoints := len(getSpawnPoints(config.FieldWidth, config.FieldHeight))
+	if arena != nil {
+		spawnPoints = len(arena.spawnPoints())
+	}
+	if spawnPoints < mode.MatchSize {
+		return fmt.Errorf("a match of %s needs %d spawn points, the map has %d", mode.Name, mode.MatchSize, spawnPoints)
+	}
+	if spawnPoints < config.Teams {
+		return fmt.Errorf("%d teams need %d spawn points, the map has %d", config.Teams, config.Teams, spawnPoints)
+	}
+	return nil
+}
+
+// NewTeamMode returns the classic game mode played in two teams.
+// The number of teams and friendly fire can still be changed in the config.
+func NewTeamMode(config Config, arena *Map) game.Mode {
+	if config.Teams == 0 {
+		config.Teams = 2
+	}
+	return game.Mode{
+		Name:        TEAM_MODE_NAME,
+		Description: "The classic bomberman game played in teams, 2v2!",
+		MatchSize:   MAX_PLAYERS,
+		MinPlayers:  max(MIN_PLAYERS, config.Teams), // Every team needs a player
+		New: func(finisher game.GameFinisher, id string, historyFilePath string) game.Game {
+			return NewClassic(finisher, id, historyFilePath, config, arena)
+		},
+	}
+}
+
+// NewFogMode returns the classic game mode with fog of war,
+// every player only sees the tiles within the view radius.
+func NewFogMode(config Config, arena *Map) game.Mode {
+	if config.ViewRadius == 0 {
+		config.ViewRadius = 4
+	}
+	return game.Mode{
+		Name:        FOG_MODE_NAME,
+		Description: "The classic bomberman game, but you only see what is close to you!",
+		MatchSize:   MIN_PLAYERS,
+		MinPlayers:  MIN_PLAYERS,
+		// Clients that don't know FOG tiles can't read the states of this mode
+		RequiredCapabilities: []string{message.CAPABILITY_FOG_OF_WAR},
+		New: func(finisher game.GameFinisher, id string, historyFilePath string) game.Game {
+			return NewClassic(finisher, id, historyFilePath, config, arena)
+		},
+	}
+}
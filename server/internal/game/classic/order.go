@@ -0,0 +1,71 @@
+package classic
+
+import (
+	"sort"
+
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+// The game state is stored in maps which have no stable iteration order.
+// Everything that can influence the outcome of a tick or ends up in a
+// state payload is therefore iterated through the helpers below so
+// identical inputs always produce identical ticks:
+//   - players are ordered by their spawn index
+//   - bombs are ordered by the order they were placed in
+//   - explosions and power-ups are ordered by position (row by row)
+
+// orderedPlayers returns all players sorted by their spawn index
+func (s *Simulation) orderedPlayers() []*Player {
+	players := make([]*Player, 0, len(s.players))
+	for _, player := range s.players {
+		players = append(players, player)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].SpawnIndex < players[j].SpawnIndex
+	})
+	return players
+}
+
+// orderedBombs returns all bombs sorted by the order they were placed in
+func (s *Simulation) orderedBombs() []*Bomb {
+	bombs := make([]*Bomb, 0, len(s.bombs))
+	for _, bomb := range s.bombs {
+		bombs = append(bombs, bomb)
+	}
+	sort.Slice(bombs, func(i, j int) bool {
+		return bombs[i].sequence < bombs[j].sequence
+	})
+	return bombs
+}
+
+// orderedExplosions returns all explosion positions sorted row by row
+func (s *Simulation) orderedExplosions() []types.Vec2 {
+	explosions := make([]types.Vec2, 0, len(s.explosions))
+	for _, explosion := range s.explosions {
+		explosions = append(explosions, explosion.Pos)
+	}
+	sort.Slice(explosions, func(i, j int) bool {
+		return isBefore(explosions[i], explosions[j])
+	})
+	return explosions
+}
+
+// orderedPowerUps returns all power-ups sorted row by row
+func (s *Simulation) orderedPowerUps() []*PowerUp {
+	powerUps := make([]*PowerUp, 0, len(s.powerUps))
+	for _, powerUp := range s.powerUps {
+		powerUps = append(powerUps, powerUp)
+	}
+	sort.Slice(powerUps, func(i, j int) bool {
+		return isBefore(powerUps[i].Pos, powerUps[j].Pos)
+	})
+	return powerUps
+}
+
+// isBefore reports whether a comes before b in reading order
+func isBefore(a, b types.Vec2) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
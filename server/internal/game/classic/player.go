@@ -3,14 +3,49 @@ package classic
 import "github.com/N3moAhead/bombahead/server/pkg/types"
 
 type Player struct {
-	ID        string     `json:"id"`
-	Pos       types.Vec2 `json:"pos"`
-	Health    int        `json:"health"`
-	Score     int        `json:"score"`
-	AuthToken string
-	NextMove  PlayerMove
+	ID           string     `json:"id"`
+	Pos          types.Vec2 `json:"pos"`
+	Health       int        `json:"health"`
+	Score        int        `json:"score"`
+	MaxBombs     int        `json:"maxBombs"`     // Maximum number of bombs the player can have on the field at once
+	BlastRadius  int        `json:"blastRadius"`  // Explosion radius of the bombs placed by the player
+	CanPassBombs bool       `json:"canPassBombs"` // Whether the player can walk over bombs
+	CanKickBombs bool       `json:"canKickBombs"` // Whether the player kicks the bombs it walks into
+	HasShield    bool       `json:"hasShield"`    // A shield absorbs the next hit
+	SpawnIndex   int        `json:"spawnIndex"`   // Index of the spawn point, also defines the processing order
+	Team         int        `json:"team"`         // Team of the player starting at 1, 0 in free for all games
+	AuthToken    string
+	NextMove     PlayerMove
+	NextBomb     bool // Place a bomb before executing NextMove
 }
 
-func (p *Player) HandleInput(payload ClassicInputPayload) {
-	p.NextMove = payload.Move
+func (p *Player) applyPowerUp(powerUpType PowerUpType) {
+	switch powerUpType {
+	case EXTRA_BOMB:
+		p.MaxBombs += 1
+	case BLAST_RADIUS:
+		p.BlastRadius += 1
+	case BOMB_PASS:
+		p.CanPassBombs = true
+	case BOMB_KICK:
+		p.CanKickBombs = true
+	case SHIELD:
+		p.HasShield = true
+	}
+}
+
+// getState returns the public state of the player
+func (p *Player) getState() PlayerState {
+	return PlayerState{
+		ID:           p.ID,
+		Pos:          p.Pos,
+		Health:       p.Health,
+		Score:        p.Score,
+		MaxBombs:     p.MaxBombs,
+		BlastRadius:  p.BlastRadius,
+		CanPassBombs: p.CanPassBombs,
+		CanKickBombs: p.CanKickBombs,
+		HasShield:    p.HasShield,
+		Team:         p.Team,
+	}
 }
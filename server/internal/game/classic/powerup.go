@@ -0,0 +1,58 @@
+package classic
+
+import "github.com/N3moAhead/bombahead/server/pkg/types"
+
+type PowerUpType string
+
+const (
+	EXTRA_BOMB   PowerUpType = "EXTRA_BOMB"   // One more bomb can be placed at once
+	BLAST_RADIUS PowerUpType = "BLAST_RADIUS" // Explosions reach one more tile
+	BOMB_PASS    PowerUpType = "BOMB_PASS"    // The player can walk over bombs
+	BOMB_KICK    PowerUpType = "BOMB_KICK"    // Walking into a bomb kicks it, it slides until something blocks it
+	SHIELD       PowerUpType = "SHIELD"       // Absorbs the next hit of an explosion
+)
+
+var powerUpTypes = []PowerUpType{EXTRA_BOMB, BLAST_RADIUS, BOMB_PASS, BOMB_KICK, SHIELD}
+
+type PowerUp struct {
+	Pos  types.Vec2  `json:"pos"`
+	Type PowerUpType `json:"type"`
+}
+
+func (s *Simulation) containsPowerUp(pos types.Vec2) bool {
+	_, ok := s.powerUps[pos.String()]
+	return ok
+}
+
+// dropPowerUps randomly places power-ups on the tiles of destroyed boxes
+func (s *Simulation) dropPowerUps(destroyedBoxes []types.Vec2) {
+	for _, pos := range destroyedBoxes {
+		if s.containsPowerUp(pos) || s.rng.Float64() >= s.config.PowerUpSpawnRate {
+			continue
+		}
+		powerUpType := powerUpTypes[s.rng.Intn(len(powerUpTypes))]
+		s.powerUps[pos.String()] = &PowerUp{Pos: pos, Type: powerUpType}
+	}
+}
+
+// collectPowerUps hands every power-up a player is standing on to that player.
+// If several players share the tile the one with the lowest spawn index gets it.
+func (s *Simulation) collectPowerUps() {
+	for _, player := range s.orderedPlayers() {
+		if player.Health <= 0 {
+			continue
+		}
+		powerUp, ok := s.powerUps[player.Pos.String()]
+		if !ok {
+			continue
+		}
+		player.applyPowerUp(powerUp.Type)
+		delete(s.powerUps, powerUp.Pos.String())
+		s.emit(GameEvent{
+			Type:     EVENT_POWERUP_COLLECTED,
+			Pos:      powerUp.Pos,
+			PlayerID: player.ID,
+			PowerUp:  powerUp.Type,
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package classic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+// Divergence describes the first tick where a re-simulated game differs from its history
+type Divergence struct {
+	Tick     int    // Index of the tick in GameHistory.Ticks
+	Reason   string // Which part of the state differs
+	Expected string // The recorded value as JSON
+	Actual   string // The re-simulated value as JSON
+}
+
+// NewSimulationFromHistory recreates the simulation as it was at the start of the recorded game
+func NewSimulationFromHistory(gh GameHistory) (*Simulation, error) {
+	validate := gh.Config.Validate
+	if gh.MapName != DEFAULT_MAP_NAME {
+		validate = gh.Config.validateForMap
+	}
+	if err := validate(); err != nil {
+		return nil, fmt.Errorf("history contains no valid config: %w", err)
+	}
+	initialField := gh.InitialField
+	if initialField.Width*initialField.Height != len(initialField.Field) {
+		return nil, fmt.Errorf(
+			"initial field has %d tiles, expected %dx%d",
+			len(initialField.Field),
+			initialField.Width,
+			initialField.Height,
+		)
+	}
+	if len(gh.InitialPlayers) == 0 {
+		return nil, fmt.Errorf("history contains no initial players")
+	}
+
+	field := &Field{
+		width:       initialField.Width,
+		height:      initialField.Height,
+		tiles:       append([]Tile{}, initialField.Field...),
+		spawnPoints: make([]types.Vec2, 0, len(gh.InitialPlayers)),
+	}
+	for _, p := range gh.InitialPlayers {
+		field.spawnPoints = append(field.spawnPoints, p.Pos)
+	}
+
+	s := &Simulation{
+		config:     gh.Config,
+		mapName:    gh.MapName,
+		seed:       gh.Seed,
+		rng:        rand.New(rand.NewSource(gh.Seed)),
+		field:      field,
+		players:    make(map[string]*Player),
+		bombs:      make(map[string]*Bomb),
+		explosions: make(map[string]Explosion),
+		powerUps:   make(map[string]*PowerUp),
+		maxPlayers: len(gh.InitialPlayers),
+	}
+	for i, p := range gh.InitialPlayers {
+		s.players[p.ID] = &Player{
+			ID:           p.ID,
+			Pos:          p.Pos,
+			Health:       p.Health,
+			Score:        p.Score,
+			MaxBombs:     p.MaxBombs,
+			BlastRadius:  p.BlastRadius,
+			CanPassBombs: p.CanPassBombs,
+			CanKickBombs: p.CanKickBombs,
+			HasShield:    p.HasShield,
+			SpawnIndex:   i,
+			Team:         p.Team,
+			NextMove:     NO_INPUT_DEFINED,
+		}
+	}
+	return s, nil
+}
+
+// VerifyHistory re-runs the recorded game with the recorded moves and compares
+// every tick with the history. It returns the first divergence or nil if the
+// history matches the engine exactly.
+func VerifyHistory(gh GameHistory) (*Divergence, error) {
+	s, err := NewSimulationFromHistory(gh)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordHistory()
+
+	for i, recorded := range gh.Ticks {
+		if s.IsGameOver() {
+			return &Divergence{
+				Tick:     i,
+				Reason:   "game continues after it was over",
+				Expected: fmt.Sprintf("game over after %d ticks", i),
+				Actual:   fmt.Sprintf("%d ticks recorded", len(gh.Ticks)),
+			}, nil
+		}
+
+		// Players that left the game are missing in the following ticks
+		inputs := make(map[string]PlayerInput, len(recorded.Players))
+		present := make(map[string]bool, len(recorded.Players))
+		for _, p := range recorded.Players {
+			inputs[p.ID] = PlayerInput{Move: p.Move, Bomb: p.Bomb}
+			present[p.ID] = true
+			s.SetAuthToken(p.ID, p.AuthToken)
+		}
+		for playerID := range s.players {
+			if !present[playerID] {
+				s.RemovePlayer(playerID)
+			}
+		}
+
+		s.Step(inputs)
+		actual := s.history.Ticks[i]
+
+		divergence, err := compareTicks(i, recorded, actual)
+		if err != nil || divergence != nil {
+			return divergence, err
+		}
+	}
+
+	// A game which is not over after the last tick was stopped because players
+	// left, so the remaining players at that moment are unknown
+	if !s.IsGameOver() {
+		return nil, nil
+	}
+	if winningTeam := s.WinningTeam(); winningTeam != gh.WinningTeam {
+		return &Divergence{
+			Tick:     len(gh.Ticks) - 1,
+			Reason:   "winning team",
+			Expected: fmt.Sprint(gh.WinningTeam),
+			Actual:   fmt.Sprint(winningTeam),
+		}, nil
+	}
+	if winnerAuthToken := s.AuthToken(s.Winner()); winnerAuthToken != gh.WinnerAuthToken {
+		return &Divergence{
+			Tick:     len(gh.Ticks) - 1,
+			Reason:   "winner",
+			Expected: gh.WinnerAuthToken,
+			Actual:   winnerAuthToken,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// compareTicks compares the parts of two ticks one after another
+// so the divergence can name the part that differs
+func compareTicks(tick int, expected, actual TickState) (*Divergence, error) {
+	parts := []struct {
+		name     string
+		expected any
+		actual   any
+	}{
+		{"players", expected.Players, actual.Players},
+		{"bombs", expected.Bombs, actual.Bombs},
+		{"explosions", expected.Explosions, actual.Explosions},
+		{"power_ups", expected.PowerUps, actual.PowerUps},
+		{"destroyed_boxes", expected.DestroyedBoxes, actual.DestroyedBoxes},
+		{"closed_walls", expected.ClosedWalls, actual.ClosedWalls},
+		{"events", expected.Events, actual.Events},
+		{"visible", expected.Visible, actual.Visible},
+	}
+
+	for _, part := range parts {
+		expectedJSON, err := json.Marshal(part.expected)
+		if err != nil {
+			return nil, err
+		}
+		actualJSON, err := json.Marshal(part.actual)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(expectedJSON, actualJSON) {
+			return &Divergence{
+				Tick:     tick,
+				Reason:   part.name,
+				Expected: string(expectedJSON),
+				Actual:   string(actualJSON),
+			}, nil
+		}
+	}
+	return nil, nil
+}
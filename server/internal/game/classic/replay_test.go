@@ -0,0 +1,165 @@
+package classic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// recordGame plays a random game and returns its history as it is read from a file
+func recordGame(t *testing.T, config Config, arena *Map, seed int64) GameHistory {
+	t.Helper()
+	s := newTestSimulation(t, config, arena, seed, 4)
+	playRandomGame(s, seed, 1000)
+	gh := s.History().ToGameHistory(s.AuthToken(s.Winner()), s.WinningTeam(), nil)
+
+	b, err := json.Marshal(gh)
+	if err != nil {
+		t.Fatalf("marshalling the history: %v", err)
+	}
+	var decoded GameHistory
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unmarshalling the history: %v", err)
+	}
+	return decoded
+}
+
+func TestVerifyHistory(t *testing.T) {
+	// Short games always end, by the time limit if not by the players
+	short := DefaultConfig()
+	short.MaxGameTimeMs = 100 * short.TickRateMs
+	teams := short
+	teams.Teams = 2
+	fog := short
+	fog.ViewRadius = 2
+
+	tests := []struct {
+		name       string
+		config     Config
+		arena      *Map
+		modify     func(gh *GameHistory)
+		wantReason string // Empty if the history has to match
+		wantTick   int
+	}{
+		{name: "untouched", config: short},
+		{name: "untouched custom map", config: short, arena: testMap(t)},
+		{name: "untouched teams", config: teams},
+		{name: "untouched fog of war", config: fog},
+		{
+			name:   "moved player",
+			config: short,
+			modify: func(gh *GameHistory) {
+				gh.Ticks[10].Players[0].Pos.X += 1
+			},
+			wantReason: "players",
+			wantTick:   10,
+		},
+		{
+			name:   "missing bomb",
+			config: short,
+			modify: func(gh *GameHistory) {
+				for i, tick := range gh.Ticks {
+					if len(tick.Bombs) > 0 {
+						gh.Ticks[i].Bombs = tick.Bombs[1:]
+						return
+					}
+				}
+				t.Fatal("no bomb was placed in the game")
+			},
+			wantReason: "bombs",
+			wantTick:   -1,
+		},
+		{
+			name:   "other winner",
+			config: short,
+			modify: func(gh *GameHistory) {
+				gh.WinnerAuthToken = "somebody else"
+			},
+			wantReason: "winner",
+			wantTick:   -1,
+		},
+		{
+			name:   "other winning team",
+			config: teams,
+			modify: func(gh *GameHistory) {
+				gh.WinningTeam = 3
+			},
+			wantReason: "winning team",
+			wantTick:   -1,
+		},
+		{
+			name:   "tick after the game was over",
+			config: short,
+			modify: func(gh *GameHistory) {
+				gh.Ticks = append(gh.Ticks, gh.Ticks[len(gh.Ticks)-1])
+			},
+			wantReason: "game continues after it was over",
+			wantTick:   -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := recordGame(t, tt.config, tt.arena, 7)
+			if tt.modify != nil {
+				tt.modify(&gh)
+			}
+
+			divergence, err := VerifyHistory(gh)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantReason == "" {
+				if divergence != nil {
+					t.Fatalf("history diverged at tick %d in %s:\nexpected %s\nactual   %s",
+						divergence.Tick, divergence.Reason, divergence.Expected, divergence.Actual)
+				}
+				return
+			}
+			if divergence == nil {
+				t.Fatalf("expected a divergence in %s, the history matched", tt.wantReason)
+			}
+			if divergence.Reason != tt.wantReason {
+				t.Errorf("diverged in %s, expected %s", divergence.Reason, tt.wantReason)
+			}
+			if tt.wantTick >= 0 && divergence.Tick != tt.wantTick {
+				t.Errorf("diverged at tick %d, expected %d", divergence.Tick, tt.wantTick)
+			}
+		})
+	}
+}
+
+func TestVerifyHistoryRejectsInvalidHistories(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(gh *GameHistory)
+	}{
+		{"invalid config", func(gh *GameHistory) { gh.Config.TickRateMs = 0 }},
+		{"truncated field", func(gh *GameHistory) { gh.InitialField.Field = gh.InitialField.Field[1:] }},
+		{"no players", func(gh *GameHistory) { gh.InitialPlayers = nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := recordGame(t, DefaultConfig(), nil, 7)
+			tt.modify(&gh)
+			if _, err := VerifyHistory(gh); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestVerifyHistoryOfSmallestMap(t *testing.T) {
+	arena := &Map{Name: "Test", Tiles: []string{"###", "#S#", "#S#", "###"}}
+	s := newTestSimulation(t, DefaultConfig(), arena, 7, 2)
+	playRandomGame(s, 7, 1000)
+	gh := s.History().ToGameHistory(s.AuthToken(s.Winner()), s.WinningTeam(), nil)
+
+	divergence, err := VerifyHistory(gh)
+	if err != nil {
+		t.Fatalf("history of a valid map is rejected: %v", err)
+	}
+	if divergence != nil {
+		t.Fatalf("history diverged at tick %d in %s", divergence.Tick, divergence.Reason)
+	}
+}
@@ -0,0 +1,39 @@
+package classic
+
+// awardScore adds points to the score of a player.
+// Points for players that already left the game are dropped.
+func (s *Simulation) awardScore(playerID string, points int) {
+	if player, ok := s.players[playerID]; ok {
+		player.Score += points
+	}
+}
+
+// awardSurvivalScore rewards every living player each SurvivalScoreTicks ticks
+func (s *Simulation) awardSurvivalScore() {
+	if s.config.SurvivalScoreTicks <= 0 || s.tick%s.config.SurvivalScoreTicks != 0 {
+		return
+	}
+	for _, player := range s.players {
+		if player.Health > 0 {
+			player.Score += s.config.SurvivalScorePoints
+		}
+	}
+}
+
+// Scores returns the current score of every player by player ID
+func (s *Simulation) Scores() map[string]int {
+	scores := make(map[string]int, len(s.players))
+	for playerID, player := range s.players {
+		scores[playerID] = player.Score
+	}
+	return scores
+}
+
+// isAhead reports whether player a is ahead of player b after the time ran out,
+// first by health and then by score
+func isAhead(a *Player, b *Player) bool {
+	if a.Health != b.Health {
+		return a.Health > b.Health
+	}
+	return a.Score > b.Score
+}
@@ -0,0 +1,275 @@
+package classic
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/N3moAhead/bombahead/server/pkg/types"
+)
+
+// Simulation contains the complete state and rules of a classic game.
+// It has no goroutines, timers or I/O and only advances when Step is called,
+// so it can be used headless for bot training, tests and replay verification.
+// A Simulation is not safe for concurrent use.
+type Simulation struct {
+	config          Config
+	mapName         string     // Name of the map the field was built from
+	seed            int64      // Seed used to generate the field
+	rng             *rand.Rand // Random source for in game decisions like power-up drops
+	field           *Field
+	players         map[string]*Player   // PlayerID -> Player
+	bombs           map[string]*Bomb     // Bomb.Pos -> Bomb
+	explosions      map[string]Explosion // Explosion.Pos -> Explosion
+	powerUps        map[string]*PowerUp  // PowerUp.Pos -> PowerUp
+	events          []GameEvent          // Events of the current tick in the order they happened
+	closedWalls     []types.Vec2         // Tiles that became walls in the current tick
+	suddenDeathPath []types.Vec2         // Remaining tiles to close, computed when the sudden death starts
+	bombCount       int                  // Number of bombs placed so far, used to order the bombs
+	maxPlayers      int
+	tick            int      // Number of steps taken so far, during a step the number of the current tick
+	history         *History // Only recorded after RecordHistory was called
+}
+
+// StepEvents describes what happened during a single step
+type StepEvents struct {
+	Tick           int          // The tick that was just simulated, starting at 1
+	DestroyedBoxes []types.Vec2 // Boxes destroyed by explosions during this tick
+	GameOver       bool         // True if the game ended with this tick
+}
+
+// NewSimulation creates a new simulation. If arena is nil the default
+// labyrinth is generated, otherwise the field is built from the map.
+// The same config, map and seed always result in the same initial state.
+func NewSimulation(config Config, arena *Map, seed int64) *Simulation {
+	mapName := DEFAULT_MAP_NAME
+	var field *Field
+	if arena != nil {
+		mapName = arena.Name
+		field = NewFieldFromMap(arena, config, seed)
+		// The map defines the size of the field so the
+		// config is updated for the players and the history
+		config.FieldWidth = field.width
+		config.FieldHeight = field.height
+	} else {
+		field = NewField(config, seed)
+	}
+
+	return &Simulation{
+		config:     config,
+		mapName:    mapName,
+		seed:       seed,
+		rng:        rand.New(rand.NewSource(seed)),
+		field:      field,
+		players:    make(map[string]*Player),
+		bombs:      make(map[string]*Bomb),
+		explosions: make(map[string]Explosion),
+		powerUps:   make(map[string]*PowerUp),
+		maxPlayers: min(MAX_PLAYERS, len(field.spawnPoints)),
+	}
+}
+
+func (s *Simulation) Config() Config {
+	return s.config
+}
+
+func (s *Simulation) Tick() int {
+	return s.tick
+}
+
+func (s *Simulation) PlayerCount() int {
+	return len(s.players)
+}
+
+func (s *Simulation) MaxPlayers() int {
+	return s.maxPlayers
+}
+
+func (s *Simulation) HasPlayer(playerID string) bool {
+	_, ok := s.players[playerID]
+	return ok
+}
+
+// AddPlayer places a new player on the first free spawn point
+func (s *Simulation) AddPlayer(playerID string, authToken string) error {
+	if len(s.players) >= s.maxPlayers {
+		return fmt.Errorf("already full, can't add more players (%d/%d)", len(s.players), s.maxPlayers)
+	}
+	if _, exists := s.players[playerID]; exists {
+		return fmt.Errorf("player %s already exists", playerID)
+	}
+
+	// Assign the first spawn point which is not taken by another player
+	playerIndex := s.freeSpawnIndex()
+	spawnPos := s.field.spawnPoints[playerIndex]
+
+	s.players[playerID] = &Player{
+		ID:          playerID,
+		Pos:         spawnPos,
+		Score:       0,
+		Health:      s.config.InitialHealth,
+		MaxBombs:    s.config.InitialMaxBombs,
+		BlastRadius: s.config.BombExplosionRadius,
+		SpawnIndex:  playerIndex,
+		Team:        s.teamOf(playerIndex),
+		NextMove:    NO_INPUT_DEFINED,
+		AuthToken:   authToken,
+	}
+	return nil
+}
+
+// freeSpawnIndex returns the lowest spawn index no player is using.
+// The caller has to ensure that the game is not full.
+func (s *Simulation) freeSpawnIndex() int {
+	taken := make(map[int]bool, len(s.players))
+	for _, player := range s.players {
+		taken[player.SpawnIndex] = true
+	}
+	index := 0
+	for taken[index] {
+		index += 1
+	}
+	return index
+}
+
+// RemovePlayer removes the player and reports whether it was part of the game
+func (s *Simulation) RemovePlayer(playerID string) bool {
+	if _, ok := s.players[playerID]; !ok {
+		return false
+	}
+	delete(s.players, playerID)
+	return true
+}
+
+// SetAuthToken updates the auth token of a player and reports whether the player exists
+func (s *Simulation) SetAuthToken(playerID string, authToken string) bool {
+	player, ok := s.players[playerID]
+	if ok {
+		player.AuthToken = authToken
+	}
+	return ok
+}
+
+// RecordHistory starts recording every following step, capturing the current field as initial field
+func (s *Simulation) RecordHistory() {
+	state := s.getGameState()
+	s.history = NewHistory(s.seed, s.config, s.mapName, state.Field, state.Players)
+}
+
+// History returns the recorded history or nil if it is not recorded
+func (s *Simulation) History() *History {
+	return s.history
+}
+
+// State returns the current state as it is sent to the players
+func (s *Simulation) State() ClassicStatePayload {
+	return s.getGameState()
+}
+
+// Step advances the game by one tick. Players without an entry
+// in inputs are treated as if they had sent no input.
+func (s *Simulation) Step(inputs map[string]PlayerInput) (ClassicStatePayload, StepEvents) {
+	for playerID, player := range s.players {
+		input, ok := inputs[playerID]
+		if !ok {
+			input = PlayerInput{Move: NO_INPUT_DEFINED}
+		}
+		player.NextMove = input.Move
+		player.NextBomb = input.Bomb
+	}
+
+	s.tick += 1
+	s.events = nil
+	s.closedWalls = nil
+	destroyedBoxes := s.update()
+
+	if s.history != nil {
+		s.history.RecordTick(
+			s.orderedPlayers(),
+			s.orderedBombs(),
+			s.orderedExplosions(),
+			s.orderedPowerUps(),
+			destroyedBoxes,
+			s.closedWalls,
+			s.events,
+			s.visibleTiles(),
+		)
+	}
+	state := s.getGameState()
+	s.resetPlayerInputs()
+
+	return state, StepEvents{
+		Tick:           s.tick,
+		DestroyedBoxes: destroyedBoxes,
+		GameOver:       s.IsGameOver(),
+	}
+}
+
+// IsTimeOut reports whether the game reached its maximum game time
+func (s *Simulation) IsTimeOut() bool {
+	return s.tick >= s.config.MaxGameTimeMs/s.config.TickRateMs
+}
+
+// IsGameOver reports whether one or less players, or in team games teams,
+// are left alive or the time ran out
+func (s *Simulation) IsGameOver() bool {
+	return s.isGameOver() || s.IsTimeOut()
+}
+
+// Winner returns the ID of the winning player or an empty string if it's a draw.
+// In team games there is no single winner, see WinningTeam.
+func (s *Simulation) Winner() string {
+	winner := ""
+	if s.config.Teams > 0 {
+		return winner
+	}
+	if s.IsTimeOut() {
+		var bestPlayer *Player = nil
+		isUnique := true
+		// The time ran out so we will check if one of the
+		// players has more health left then the others.
+		// Players with the same health are compared by their score.
+		for _, player := range s.orderedPlayers() {
+			if bestPlayer == nil || isAhead(player, bestPlayer) {
+				bestPlayer = player
+				isUnique = true
+			} else if !isAhead(bestPlayer, player) {
+				isUnique = false
+			}
+		}
+
+		if isUnique && bestPlayer != nil {
+			winner = bestPlayer.ID
+		}
+	} else {
+		// There can only be one winner
+		// The field will be left empty, if
+		// it's a draw
+		for _, player := range s.orderedPlayers() {
+			if player.Health > 0 {
+				if winner != "" {
+					return ""
+				}
+				winner = player.ID
+			}
+		}
+	}
+	return winner
+}
+
+// HistoryKey returns the key of a player in the maps of the history. Player IDs
+// change with every connection so players are recorded by their auth token,
+// players without one, like every player of the live hub, by their ID.
+func (s *Simulation) HistoryKey(playerID string) string {
+	if authToken := s.AuthToken(playerID); authToken != "" {
+		return authToken
+	}
+	return playerID
+}
+
+// AuthToken returns the auth token of a player or an empty string if the player does not exist
+func (s *Simulation) AuthToken(playerID string) string {
+	if player, ok := s.players[playerID]; ok {
+		return player.AuthToken
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package classic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+var testMoves = []PlayerMove{DO_NOTHING, MOVE_UP, MOVE_RIGHT, MOVE_DOWN, MOVE_LEFT, PLACE_BOMB}
+
+// newTestSimulation creates a recording simulation with the given number of players
+func newTestSimulation(t *testing.T, config Config, arena *Map, seed int64, players int) *Simulation {
+	t.Helper()
+	s := NewSimulation(config, arena, seed)
+	for i := range players {
+		if err := s.AddPlayer(fmt.Sprintf("player-%d", i), fmt.Sprintf("token-%d", i)); err != nil {
+			t.Fatalf("adding player %d: %v", i, err)
+		}
+	}
+	s.RecordHistory()
+	return s
+}
+
+// playRandomGame steps the simulation with random but reproducible inputs
+// until the game is over or maxTicks passed. Returns every state in order.
+func playRandomGame(s *Simulation, inputSeed int64, maxTicks int) []ClassicStatePayload {
+	rng := rand.New(rand.NewSource(inputSeed))
+	states := []ClassicStatePayload{s.State()}
+	for range maxTicks {
+		if s.IsGameOver() {
+			break
+		}
+		inputs := make(map[string]PlayerInput)
+		for _, player := range s.orderedPlayers() {
+			inputs[player.ID] = PlayerInput{
+				Move: testMoves[rng.Intn(len(testMoves))],
+				Bomb: rng.Intn(4) == 0,
+			}
+		}
+		state, _ := s.Step(inputs)
+		states = append(states, state)
+	}
+	return states
+}
+
+func testMap(t *testing.T) *Map {
+	t.Helper()
+	arena := &Map{
+		Name:        "Test",
+		RandomBoxes: true,
+		Tiles: []string{
+			"#########",
+			"#S.....S#",
+			"#.#.#.#.#",
+			"#...B...#",
+			"#.#.#.#.#",
+			"#S.....S#",
+			"#########",
+		},
+	}
+	if err := arena.Validate(); err != nil {
+		t.Fatalf("test map is invalid: %v", err)
+	}
+	return arena
+}
+
+func TestStepIsDeterministic(t *testing.T) {
+	teams := DefaultConfig()
+	teams.Teams = 2
+	fog := DefaultConfig()
+	fog.ViewRadius = 2
+	suddenDeath := DefaultConfig()
+	suddenDeath.SuddenDeathTicks = 20
+
+	tests := []struct {
+		name    string
+		config  Config
+		arena   *Map
+		players int
+	}{
+		{"default field", DefaultConfig(), nil, 4},
+		{"two players", DefaultConfig(), nil, 2},
+		{"custom map", DefaultConfig(), testMap(t), 4},
+		{"teams", teams, nil, 4},
+		{"fog of war", fog, nil, 4},
+		{"sudden death", suddenDeath, nil, 4},
+	}
+
+	for _, tt := range tests {
+		for _, seed := range []int64{0, 1, 42, 1337} {
+			t.Run(fmt.Sprintf("%s/seed %d", tt.name, seed), func(t *testing.T) {
+				first := newTestSimulation(t, tt.config, tt.arena, seed, tt.players)
+				second := newTestSimulation(t, tt.config, tt.arena, seed, tt.players)
+				firstStates := playRandomGame(first, seed, 300)
+				secondStates := playRandomGame(second, seed, 300)
+
+				if len(firstStates) != len(secondStates) {
+					t.Fatalf("first game lasted %d ticks, second %d", len(firstStates), len(secondStates))
+				}
+				for i := range firstStates {
+					firstJSON, _ := json.Marshal(firstStates[i])
+					secondJSON, _ := json.Marshal(secondStates[i])
+					if string(firstJSON) != string(secondJSON) {
+						t.Fatalf("states of tick %d differ:\n%s\n%s", i, firstJSON, secondJSON)
+					}
+				}
+
+				firstHistory, _ := json.Marshal(first.History())
+				secondHistory, _ := json.Marshal(second.History())
+				if string(firstHistory) != string(secondHistory) {
+					t.Fatal("the recorded histories differ")
+				}
+			})
+		}
+	}
+}
+
+func TestSeedChangesField(t *testing.T) {
+	first := NewSimulation(DefaultConfig(), nil, 1).State().Field
+	second := NewSimulation(DefaultConfig(), nil, 2).State().Field
+	firstJSON, _ := json.Marshal(first)
+	secondJSON, _ := json.Marshal(second)
+	if string(firstJSON) == string(secondJSON) {
+		t.Fatal("different seeds generated the same field")
+	}
+}
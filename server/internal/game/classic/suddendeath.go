@@ -0,0 +1,89 @@
+package classic
+
+import "github.com/N3moAhead/bombahead/server/pkg/types"
+
+// isSuddenDeath reports whether the walls are closing in
+func (s *Simulation) isSuddenDeath() bool {
+	return s.config.SuddenDeathTicks > 0 && s.tick >= s.config.SuddenDeathTicks
+}
+
+// closeWalls turns the next tile of the spiral into a wall every
+// SuddenDeathIntervalTicks ticks once the sudden death started.
+// Everything on the tile is crushed: players are eliminated,
+// bombs are defused and boxes and power-ups disappear.
+func (s *Simulation) closeWalls() []types.Vec2 {
+	if !s.isSuddenDeath() || (s.tick-s.config.SuddenDeathTicks)%s.config.SuddenDeathIntervalTicks != 0 {
+		return nil
+	}
+	next := s.nextWall()
+	if next == nil {
+		return nil
+	}
+	pos := *next
+	s.suddenDeathPath = s.suddenDeathPath[1:]
+
+	s.field.setTile(pos.X, pos.Y, WALL)
+	delete(s.bombs, pos.String())
+	delete(s.powerUps, pos.String())
+	for _, player := range s.orderedPlayers() {
+		if player.Pos != pos || player.Health <= 0 {
+			continue
+		}
+		player.Health = 0
+		s.emit(GameEvent{
+			Type:     EVENT_PLAYER_ELIMINATED,
+			Pos:      pos,
+			PlayerID: player.ID,
+		})
+	}
+	return []types.Vec2{pos}
+}
+
+// nextWall returns the tile which becomes a wall next or nil if there
+// is no sudden death, it does not start with the next tick or no tile is left
+func (s *Simulation) nextWall() *types.Vec2 {
+	if s.config.SuddenDeathTicks <= 0 || s.tick+1 < s.config.SuddenDeathTicks {
+		return nil
+	}
+	if s.suddenDeathPath == nil {
+		s.suddenDeathPath = spiralPath(s.field.width, s.field.height)
+	}
+	// Tiles which already are walls are skipped so a wall closes on every interval
+	for len(s.suddenDeathPath) > 0 {
+		pos := s.suddenDeathPath[0]
+		if s.field.getTile(pos.X, pos.Y) != WALL {
+			return &pos
+		}
+		s.suddenDeathPath = s.suddenDeathPath[1:]
+	}
+	return nil
+}
+
+// spiralPath returns all tiles inside the border of the field, clockwise
+// from the top left corner, ring by ring towards the center
+func spiralPath(width, height int) []types.Vec2 {
+	path := make([]types.Vec2, 0, (width-2)*(height-2))
+	left, top := 1, 1
+	right, bottom := width-2, height-2
+	for left <= right && top <= bottom {
+		for x := left; x <= right; x++ {
+			path = append(path, types.NewVec2(x, top))
+		}
+		for y := top + 1; y <= bottom; y++ {
+			path = append(path, types.NewVec2(right, y))
+		}
+		if top < bottom {
+			for x := right - 1; x >= left; x-- {
+				path = append(path, types.NewVec2(x, bottom))
+			}
+		}
+		if left < right {
+			for y := bottom - 1; y > top; y-- {
+				path = append(path, types.NewVec2(left, y))
+			}
+		}
+		left, top = left+1, top+1
+		right, bottom = right-1, bottom-1
+	}
+	return path
+}
@@ -0,0 +1,85 @@
+package classic
+
+// teamOf returns the team of the player on the given spawn point.
+// Teams alternate with the spawn points, so with the default spawn points
+// and two teams the left and the right side of the field play against each other.
+func (s *Simulation) teamOf(spawnIndex int) int {
+	if s.config.Teams <= 0 {
+		return 0
+	}
+	return spawnIndex%s.config.Teams + 1
+}
+
+// isSameTeam reports whether both players are in the same team
+func isSameTeam(a *Player, b *Player) bool {
+	return a.Team != 0 && a.Team == b.Team
+}
+
+// livingTeams returns the teams which have at least one living player
+func (s *Simulation) livingTeams() map[int]bool {
+	teams := make(map[int]bool)
+	for _, player := range s.players {
+		if player.Health > 0 {
+			teams[player.Team] = true
+		}
+	}
+	return teams
+}
+
+// Teams returns the team of every player by player ID or nil in free for all games
+func (s *Simulation) Teams() map[string]int {
+	if s.config.Teams <= 0 {
+		return nil
+	}
+	teams := make(map[string]int, len(s.players))
+	for playerID, player := range s.players {
+		teams[playerID] = player.Team
+	}
+	return teams
+}
+
+// WinningTeam returns the winning team or 0 if it's a draw or not a team game.
+// If the time ran out the team with the most health left wins,
+// teams with the same health are compared by their score.
+func (s *Simulation) WinningTeam() int {
+	if s.config.Teams <= 0 {
+		return 0
+	}
+
+	if !s.IsTimeOut() {
+		living := s.livingTeams()
+		if len(living) != 1 {
+			return 0
+		}
+		for team := range living {
+			return team
+		}
+	}
+
+	health := make(map[int]int)
+	score := make(map[int]int)
+	for _, player := range s.players {
+		health[player.Team] += max(player.Health, 0)
+		score[player.Team] += player.Score
+	}
+
+	winner := 0
+	isUnique := true
+	for team := 1; team <= s.config.Teams; team++ {
+		if _, ok := health[team]; !ok {
+			continue
+		}
+		if winner == 0 ||
+			health[team] > health[winner] ||
+			(health[team] == health[winner] && score[team] > score[winner]) {
+			winner = team
+			isUnique = true
+		} else if health[team] == health[winner] && score[team] == score[winner] {
+			isUnique = false
+		}
+	}
+	if !isUnique {
+		return 0
+	}
+	return winner
+}
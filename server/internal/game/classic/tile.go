@@ -6,4 +6,5 @@ const (
 	AIR  Tile = "AIR"
 	WALL Tile = "WALL"
 	BOX  Tile = "BOX"
+	FOG  Tile = "FOG" // Out of sight, only sent in fog of war games
 )
@@ -7,14 +7,17 @@ import "github.com/N3moAhead/bombahead/server/internal/message"
 type Player interface {
 	GetID() string
 	GetAuthToken() string
+	HasCapability(capability string) bool // Whether the player negotiated the protocol capability
 	SendMessage(msgType message.MessageType, payload any) error
 }
 
 // After a game is finished a game result should be returned
 // To help us update all the scores
 type GameResult struct {
-	Winner string
-	Scores map[string]int // Map from PlayerID to game scores
+	Winner      string
+	WinningTeam int            // 0 if no team won or the game has no teams
+	Scores      map[string]int // Map from PlayerID to the lobby score the player earned, only wins count
+	Points      map[string]int // Map from PlayerID to the points scored during the game including the win bonus
 }
 
 type GameFinisher interface {
@@ -25,7 +28,14 @@ type Game interface {
 	Start()                                           // Starts the game
 	AddPlayer(player Player) error                    // Adds a new player to the game
 	RemovePlayer(player Player)                       // Removes a playser from the game
+	DisconnectPlayer(player Player)                   // Keeps the player in the game without sending it anything
+	ReconnectPlayer(player Player) error              // Attaches a new connection to a disconnected player
+	AddSpectator(spectator Player)                    // Sends the game states to a spectator as well
+	RemoveSpectator(spectator Player)                 // Stops sending the game states to a spectator
 	HandleMessage(player Player, msg message.Message) // Handles incoming user input
 	Stop()                                            // Stops the game
 	GetID() string                                    // Returns the game id
+	GetConfig() any                                   // Returns the game specific configuration
+	GetTeams() map[string]int                         // Returns the team of every player, nil if the game has no teams
+	GetTick() int                                     // Returns the number of ticks simulated so far
 }
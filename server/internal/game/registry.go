@@ -0,0 +1,92 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+// Factory creates a new game instance of a game mode
+type Factory func(finisher GameFinisher, id string, historyFilePath string) Game
+
+// Mode describes a playable game variant
+type Mode struct {
+	Name        string  // Unique name clients use to select the mode
+	Description string  // Short description shown to the clients
+	New         Factory // Creates a new game of this mode
+	MatchSize   int     // Number of players matchmaking puts into one game
+	MinPlayers  int     // Fewest players a game of this mode can start with, e.g. in a room
+
+	// Protocol capabilities a client has to negotiate to play this mode
+	RequiredCapabilities []string
+}
+
+// MissingCapability returns the first required capability the player did
+// not negotiate, or an empty string if the player can play this mode
+func (m Mode) MissingCapability(player Player) string {
+	for _, capability := range m.RequiredCapabilities {
+		if !player.HasCapability(capability) {
+			return capability
+		}
+	}
+	return ""
+}
+
+// Registry maps the names of all playable game modes to their factories
+type Registry struct {
+	modes map[string]Mode
+	order []string // Names in registration order, the first one is the default mode
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		modes: make(map[string]Mode),
+		order: make([]string, 0),
+	}
+}
+
+// Register adds a new game mode. Mode names have to be unique.
+func (r *Registry) Register(mode Mode) error {
+	if mode.Name == "" {
+		return fmt.Errorf("game mode without a name can't be registered")
+	}
+	if mode.New == nil {
+		return fmt.Errorf("game mode %s has no factory", mode.Name)
+	}
+	if mode.MatchSize < 0 {
+		return fmt.Errorf("game mode %s has a negative match size", mode.Name)
+	}
+	if _, exists := r.modes[mode.Name]; exists {
+		return fmt.Errorf("game mode %s is already registered", mode.Name)
+	}
+	r.modes[mode.Name] = mode
+	r.order = append(r.order, mode.Name)
+	return nil
+}
+
+// Get returns the mode with the given name. An empty name resolves to the default mode.
+func (r *Registry) Get(name string) (Mode, bool) {
+	if name == "" {
+		return r.Default()
+	}
+	mode, ok := r.modes[name]
+	return mode, ok
+}
+
+// Default returns the first registered mode
+func (r *Registry) Default() (Mode, bool) {
+	if len(r.order) == 0 {
+		return Mode{}, false
+	}
+	return r.modes[r.order[0]], true
+}
+
+// GameInfos lists all registered modes in registration order
+func (r *Registry) GameInfos() []message.GameInfo {
+	infos := make([]message.GameInfo, 0, len(r.order))
+	for _, name := range r.order {
+		mode := r.modes[name]
+		infos = append(infos, message.GameInfo{Name: mode.Name, Description: mode.Description})
+	}
+	return infos
+}
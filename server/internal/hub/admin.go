@@ -0,0 +1,171 @@
+package hub
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+// KICK_REASON is sent in the close frame of a client an operator kicked
+const KICK_REASON = "kicked by an operator"
+
+// ClientInfo describes a connected client for the operators
+type ClientInfo struct {
+	ID       string `json:"id"`
+	IsReady  bool   `json:"isReady"`
+	Score    int    `json:"score"`
+	GameMode string `json:"gameMode"`
+	GameID   string `json:"gameId,omitempty"` // Empty while the client is in the lobby
+	Room     string `json:"room,omitempty"`   // Join code of the client's private room
+}
+
+// GameInfo describes a running game for the operators
+type GameInfo struct {
+	ID         string   `json:"id"`
+	Mode       string   `json:"mode"`
+	Tick       int      `json:"tick"`
+	Players    []string `json:"players"`
+	Spectators int      `json:"spectators"`
+}
+
+// runInHub executes the request on the goroutine of the hub and waits until
+// it is done, so operators never race with the lobby or the matchmaking
+func (h *Hub) runInHub(request func()) {
+	done := make(chan struct{})
+	h.admin <- func() {
+		defer close(done)
+		request()
+	}
+	<-done
+}
+
+// Clients lists all connected clients ordered by their IDs
+func (h *Hub) Clients() []ClientInfo {
+	clients := []ClientInfo{}
+	h.runInHub(func() {
+		h.gameMutex.RLock()
+		defer h.gameMutex.RUnlock()
+		for client := range h.clients {
+			info := ClientInfo{
+				ID:       client.GetID(),
+				IsReady:  client.IsReady(),
+				Score:    client.GetScore(),
+				GameMode: client.GetGameMode(),
+				GameID:   h.clientToGame[client],
+			}
+			if room, ok := h.clientToRoom[client]; ok {
+				info.Room = room.Code
+			}
+			clients = append(clients, info)
+		}
+	})
+	slices.SortFunc(clients, func(a, b ClientInfo) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return clients
+}
+
+// Games lists all running games ordered by their IDs
+func (h *Hub) Games() []GameInfo {
+	games := []GameInfo{}
+	h.runInHub(func() {
+		h.gameMutex.RLock()
+		defer h.gameMutex.RUnlock()
+		for gameID, activeGame := range h.activeGames {
+			info := GameInfo{
+				ID:      gameID,
+				Mode:    h.gameStarts[gameID].Name,
+				Tick:    activeGame.GetTick(),
+				Players: []string{},
+			}
+			for client, gid := range h.clientToGame {
+				if gid == gameID {
+					info.Players = append(info.Players, client.GetID())
+				}
+			}
+			slices.Sort(info.Players)
+			for _, gid := range h.spectators {
+				if gid == gameID {
+					info.Spectators++
+				}
+			}
+			games = append(games, info)
+		}
+	})
+	slices.SortFunc(games, func(a, b GameInfo) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return games
+}
+
+// StopGame ends a running game early, its players return to the lobby.
+// Returns false if there is no game with the given ID.
+func (h *Hub) StopGame(gameID string) bool {
+	found := false
+	h.runInHub(func() {
+		h.gameMutex.RLock()
+		activeGame, ok := h.activeGames[gameID]
+		h.gameMutex.RUnlock()
+		if !ok {
+			return
+		}
+		found = true
+		log.Warn("An operator stopped game %s", gameID)
+		// Stopping the game reports it as finished, which needs the gameMutex
+		activeGame.Stop()
+	})
+	return found
+}
+
+// KickClient closes the connection of a player or spectator. A kicked player
+// can't resume its game. Returns false if there is no client with the given ID.
+func (h *Hub) KickClient(clientID string) bool {
+	found := false
+	h.runInHub(func() {
+		h.gameMutex.Lock()
+		defer h.gameMutex.Unlock()
+		for client := range h.clients {
+			if client.GetID() != clientID {
+				continue
+			}
+			found = true
+			// Without a resume token the player leaves its game once the connection is closed
+			delete(h.resumeTokens, client)
+			client.CloseWithReason(KICK_REASON)
+			log.Warn("An operator kicked client %s", clientID)
+			return
+		}
+		for spectator := range h.spectators {
+			if spectator.GetID() != clientID {
+				continue
+			}
+			found = true
+			spectator.CloseWithReason(KICK_REASON)
+			log.Warn("An operator kicked spectator %s", clientID)
+			return
+		}
+	})
+	return found
+}
+
+// BroadcastNotice sends a notice to every connected client and spectator
+func (h *Hub) BroadcastNotice(text string) {
+	h.runInHub(func() {
+		log.Info("Broadcasting a notice of the operators: %s", text)
+		payload := message.NoticePayload{Message: text}
+		h.broadcastMessageInternal(message.Notice, payload)
+
+		h.gameMutex.RLock()
+		spectators := make([]Client, 0, len(h.spectators))
+		for spectator := range h.spectators {
+			spectators = append(spectators, spectator)
+		}
+		h.gameMutex.RUnlock()
+		for _, spectator := range spectators {
+			if err := spectator.SendMessage(message.Notice, payload); err != nil {
+				log.Error("Error sending the notice to spectator %s: %v", spectator.GetID(), err)
+			}
+		}
+	})
+}
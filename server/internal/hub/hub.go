@@ -2,11 +2,10 @@ package hub
 
 import (
 	"encoding/json"
+	"fmt"
 	"sync"
-	"time"
 
 	"github.com/N3moAhead/bombahead/server/internal/game"
-	"github.com/N3moAhead/bombahead/server/internal/game/classic"
 	"github.com/N3moAhead/bombahead/server/internal/message"
 	"github.com/google/uuid"
 )
@@ -23,6 +22,13 @@ type Client interface {
 	Close()
 	StartPumps()
 	SetAuthToken(authToken string)
+	SetGameMode(mode string)
+	GetGameMode() string
+	SetProtocol(version int, capabilities []string)
+	GetProtocolVersion() int
+	HasCapability(capability string) bool
+	CloseWithReason(reason string)
+	Resume(previous Client) // Takes over the identity of a client whose connection dropped
 }
 
 type hubMessage struct {
@@ -30,35 +36,58 @@ type hubMessage struct {
 	message message.Message
 }
 
-// GameDefinition can later be used for diffrent versions
-// or variants for bomberman games...
-type GameDefinition struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-}
-
 type Hub struct {
-	clients        map[Client]bool
-	incoming       chan hubMessage
-	Register       chan Client
-	unregister     chan Client
-	activeGames    map[string]game.Game
-	availableGames []message.GameInfo
-	clientToGame   map[Client]string
-	gameMutex      sync.RWMutex
+	clients      map[Client]bool
+	incoming     chan hubMessage
+	Register     chan Client
+	Spectate     chan Client // Registers a client that only watches games
+	unregister   chan Client
+	admin        chan func() // Requests of the operators, run on the hub goroutine
+	activeGames  map[string]game.Game
+	gameModes    *game.Registry
+	clientToGame map[Client]string
+	gameMutex    sync.RWMutex
+
+	gameStarts    map[string]message.GameStartPayload // GameID -> Start message of a running game
+	resumeTokens  map[Client]string                   // Client -> Token to resume its running game
+	disconnected  map[string]*disconnectedPlayer      // Resume token -> Player waiting for a reconnect
+	resumeExpired chan string
+
+	spectators map[Client]string // Spectator -> GameID it watches, empty if it watches nothing
+
+	rooms        map[string]*Room // Join code -> Private room
+	clientToRoom map[Client]*Room
+
+	queue        []Client // Ready lobby clients in the order they became ready
+	matchByScore bool     // Matches players with similar scores instead of strictly first come, first served
 }
 
-func NewHub() *Hub {
+// NewHub creates a new hub which lets players choose from the given game modes.
+// With matchByScore the matchmaking prefers opponents with a similar score.
+func NewHub(gameModes *game.Registry, matchByScore bool) *Hub {
 	return &Hub{
-		incoming:   make(chan hubMessage, 2048),
-		Register:   make(chan Client),
-		unregister: make(chan Client),
-		availableGames: []message.GameInfo{
-			{Name: "Classic", Description: "The classic and simple bomberman game!"},
-		},
+		incoming:     make(chan hubMessage, 2048),
+		Register:     make(chan Client),
+		Spectate:     make(chan Client),
+		unregister:   make(chan Client),
+		admin:        make(chan func()),
+		gameModes:    gameModes,
 		clients:      make(map[Client]bool),
 		activeGames:  make(map[string]game.Game),
 		clientToGame: make(map[Client]string),
+
+		gameStarts:    make(map[string]message.GameStartPayload),
+		resumeTokens:  make(map[Client]string),
+		disconnected:  make(map[string]*disconnectedPlayer),
+		resumeExpired: make(chan string),
+
+		spectators: make(map[Client]string),
+
+		rooms:        make(map[string]*Room),
+		clientToRoom: make(map[Client]*Room),
+
+		queue:        make([]Client, 0),
+		matchByScore: matchByScore,
 	}
 }
 
@@ -79,11 +108,14 @@ func (h *Hub) Run() {
 		case client := <-h.Register:
 			h.gameMutex.Lock()
 			h.clients[client] = true
+			h.updateGauges()
 			h.gameMutex.Unlock()
 			log.Info("Client %s registered. Total clients: %d", client.GetID(), len(h.clients))
 			welcomePayload := message.WelcomeMessage{
-				ClientID:     client.GetID(),
-				CurrentGames: h.availableGames,
+				ClientID:        client.GetID(),
+				CurrentGames:    h.gameModes.GameInfos(),
+				ProtocolVersion: message.MIN_PROTOCOL_VERSION,
+				Capabilities:    []string{}, // Nothing is negotiated before the hello
 			}
 			err := client.SendMessage(message.Welcome, welcomePayload)
 			if err != nil {
@@ -91,31 +123,74 @@ func (h *Hub) Run() {
 			}
 			h.broadcastLobbyUpdate()
 
+		case spectator := <-h.Spectate:
+			h.gameMutex.Lock()
+			h.spectators[spectator] = ""
+			h.updateGauges()
+			h.gameMutex.Unlock()
+			log.Info("Spectator %s registered", spectator.GetID())
+			welcomePayload := message.WelcomeMessage{
+				ClientID:        spectator.GetID(),
+				CurrentGames:    h.gameModes.GameInfos(),
+				ProtocolVersion: message.MIN_PROTOCOL_VERSION,
+				Capabilities:    []string{}, // Nothing is negotiated before the hello
+			}
+			if err := spectator.SendMessage(message.Welcome, welcomePayload); err != nil {
+				log.Errorln("Failed to send message to spectator ", err)
+			}
+			h.sendActiveGames(spectator)
+
 		case client := <-h.unregister:
 			h.gameMutex.Lock()
+			if gameID, ok := h.spectators[client]; ok {
+				if watchedGame, ok := h.activeGames[gameID]; ok {
+					watchedGame.RemoveSpectator(client)
+				}
+				delete(h.spectators, client)
+				client.Close()
+				h.updateGauges()
+				h.gameMutex.Unlock()
+				log.Info("Spectator %s unregistered", client.GetID())
+				continue
+			}
 			if _, ok := h.clients[client]; ok {
+				h.dequeue(client)
 				gameID, inGame := h.clientToGame[client]
-				if inGame {
+				// A player can reconnect during a grace period instead of losing the game
+				if inGame && !h.holdForResume(client, gameID) {
 					if activeGame, gameExists := h.activeGames[gameID]; gameExists {
 						activeGame.RemovePlayer(client)
 						log.Info("Removed client %s from game %s", client.GetID(), activeGame.GetID())
 					}
 					delete(h.clientToGame, client)
+					h.leaveRoom(client)
+				} else if !inGame {
+					h.leaveRoom(client)
 				}
 				delete(h.clients, client)
 				client.Close()
 				log.Warn("Client %s unregistered. Total clients: %d", client.GetID(), len(h.clients))
 			}
+			h.updateGauges()
 			h.gameMutex.Unlock()
 			h.broadcastLobbyUpdate()
-			h.checkAndPotentiallyStartGame()
+
+		case token := <-h.resumeExpired:
+			h.expireResume(token)
+
+		case request := <-h.admin:
+			request()
 
 		case hubMsg := <-h.incoming:
 			h.gameMutex.RLock()
 			gameID, inGame := h.clientToGame[hubMsg.client]
+			_, isSpectator := h.spectators[hubMsg.client]
 			h.gameMutex.RUnlock()
 
-			if inGame {
+			// Spectators never reach a game, so they can't send any inputs
+			if isSpectator {
+				h.handleSpectatorMessage(hubMsg.client, hubMsg.message)
+			} else if inGame {
 				h.gameMutex.RLock()
 				currentGame, gameExists := h.activeGames[gameID]
 				h.gameMutex.RUnlock()
@@ -137,6 +212,12 @@ func (h *Hub) Run() {
 
 func (h *Hub) handleLobbyMessage(client Client, msg message.Message) {
 	switch msg.Type {
+	case message.Hello:
+		negotiateProtocol(client, msg, h.gameModes.GameInfos())
+	case message.Resume:
+		h.resumeGame(client, msg)
+	case message.CreateRoom, message.JoinRoom, message.LeaveRoom:
+		h.handleRoomMessage(client, msg)
 	case message.PlayerStatusUpdate:
 		var payload message.PlayerStatusUpdatePayload
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
@@ -148,29 +229,69 @@ func (h *Hub) handleLobbyMessage(client Client, msg message.Message) {
 			return
 		}
 
+		mode, ok := h.gameModes.Get(payload.GameMode)
+		if !ok {
+			log.Warn("Client %s selected the unknown game mode '%s'", client.GetID(), payload.GameMode)
+			err := client.SendMessage(message.Error, message.ErrorMessage{Message: "Unknown game mode " + payload.GameMode})
+			if err != nil {
+				log.Errorln("Failed to send Error Message to client ", err)
+			}
+			return
+		}
+
+		// Players in a room only wait for the other players of their room
+		h.gameMutex.Lock()
+		room, inRoom := h.clientToRoom[client]
+		if inRoom && room.Host == client && payload.GameMode != "" && room.gameID == "" {
+			for _, player := range room.players {
+				if missing := mode.MissingCapability(player); missing != "" {
+					h.gameMutex.Unlock()
+					sendError(client, "Player "+player.GetID()+" of the room can't play "+mode.Name+" without the "+missing+" capability")
+					return
+				}
+			}
+			room.GameMode = mode.Name
+		} else if !inRoom && !canPlay(client, mode) {
+			h.gameMutex.Unlock()
+			return
+		}
+
+		client.SetGameMode(mode.Name)
 		client.SetReady(payload.IsReady)
+		if inRoom {
+			h.sendRoomUpdate(room)
+			h.checkRoom(room)
+		} else if payload.IsReady {
+			h.enqueue(client)
+		} else {
+			h.dequeue(client)
+		}
+		h.gameMutex.Unlock()
+
 		h.broadcastLobbyUpdate()
-		h.checkAndPotentiallyStartGame()
+		if !inRoom {
+			h.startQueuedGames()
+		}
 	default:
 		log.Warn("Received unhandled lobby message type '%s' from client %s", msg.Type, client.GetID())
 	}
 }
 
-func (h *Hub) selectAndStartGame() {
+// startQueuedGames starts games for the players waiting in the matchmaking queue
+func (h *Hub) startQueuedGames() {
 	h.gameMutex.Lock()
+	started := h.matchmake()
+	h.gameMutex.Unlock()
 
-	gameInfo := h.availableGames[0]
-	gameID := uuid.New().String()
-	newGame := classic.NewClassic(h, gameID, "")
-	h.activeGames[gameID] = newGame
-
-	clientsInLobby := []Client{}
-	for client := range h.clients {
-		if _, inGame := h.clientToGame[client]; !inGame {
-			clientsInLobby = append(clientsInLobby, client)
-		}
+	if started > 0 {
+		h.broadcastLobbyUpdate()
 	}
+}
 
+// startGameForMode starts a new game with the given lobby clients if enough
+// of them are ready and returns its ID, or an empty string if no game started.
+// The caller has to hold the gameMutex.
+func (h *Hub) startGameForMode(mode game.Mode, clientsInLobby []Client) string {
 	clientsReady := []Client{}
 	for _, client := range clientsInLobby {
 		if client.IsReady() {
@@ -178,44 +299,85 @@ func (h *Hub) selectAndStartGame() {
 		}
 	}
 
-	if len(clientsReady) < 2 {
-		log.Warn("Not enough players are ready and available to start a new game")
-		h.gameMutex.Unlock()
-		h.broadcastLobbyUpdate()
-		return
+	if len(clientsReady) < minPlayers(mode) {
+		log.Warn("Not enough players are ready and available to start a new %s game", mode.Name)
+		return ""
 	}
 
 	if len(clientsReady) < len(clientsInLobby) {
-		log.Info("Some players are in the lobby but still not ready we are going to wait for them")
-		h.gameMutex.Unlock()
-		h.broadcastLobbyUpdate()
-		return
+		log.Info("Some players of %s are in the lobby but still not ready we are going to wait for them", mode.Name)
+		return ""
 	}
 
+	gameID := uuid.New().String()
+	newGame := mode.New(h, gameID, "")
+	h.activeGames[gameID] = newGame
+
 	for _, client := range clientsInLobby {
+		if err := newGame.AddPlayer(client); err != nil {
+			// Nobody is left behind, the game is not started at all
+			log.Error("Error while trying to add player %s to a %s game: %v", client.GetID(), mode.Name, err)
+			h.abortGameStart(gameID, clientsInLobby, err)
+			return ""
+		}
+		log.Success("Added player %s to game %s", client.GetID(), mode.Name)
+	}
+	addedClients := clientsInLobby
+	for _, client := range addedClients {
 		h.clientToGame[client] = gameID
-		err := newGame.AddPlayer(client)
+		client.SetGameID(gameID)
+		client.SetReady(false)
+	}
+
+	startPayload := newGameStartPayload(mode, gameID, newGame)
+	h.gameStarts[gameID] = startPayload
+	for _, client := range addedClients {
+		// Every player gets its own token to resume the game after a dropped connection
+		startPayload.ResumeToken = uuid.New().String()
+		h.resumeTokens[client] = startPayload.ResumeToken
+		err := client.SendMessage(message.GameStart, startPayload)
 		if err != nil {
-			log.Error("Error while trying to add player to game %s; %v", client.GetID(), err)
-			delete(h.clientToGame, client)
-		} else {
-			client.SetGameID(gameID)
-			client.SetReady(false)
-			startPayload := message.GameStartPayload{Name: gameInfo.Name, Description: gameInfo.Description, GameID: gameID}
-			err := client.SendMessage(message.GameStart, startPayload)
-			if err != nil {
-				log.Errorln("Error while trying to send gameStartPayload to client ", err)
-			}
-			log.Success("Added player %s to game %s", client.GetID(), h.availableGames[0].Name)
+			log.Errorln("Error while trying to send gameStartPayload to client ", err)
 		}
 	}
 
+	gamesStarted.WithLabelValues(mode.Name).Inc()
+	h.updateGauges()
 	go newGame.Start()
-	log.Success("Started game %s (%s) in a new goroutine", gameInfo.Name, gameID)
+	log.Success("Started game %s (%s) in a new goroutine", mode.Name, gameID)
+	return gameID
+}
 
-	h.gameMutex.Unlock()
+// newGameStartPayload describes a new game to its players. It has to be
+// built after all players joined, only then the teams are complete.
+func newGameStartPayload(mode game.Mode, gameID string, newGame game.Game) message.GameStartPayload {
+	return message.GameStartPayload{
+		Name:        mode.Name,
+		Description: mode.Description,
+		GameID:      gameID,
+		Config:      newGame.GetConfig(),
+		Teams:       newGame.GetTeams(),
+	}
+}
 
-	h.broadcastLobbyUpdate()
+// abortGameStart drops a game that could not take all of its players. They
+// are told why and have to get ready again, otherwise the same start would
+// fail over and over. The caller has to hold the gameMutex.
+func (h *Hub) abortGameStart(gameID string, clients []Client, err error) {
+	delete(h.activeGames, gameID)
+	for _, client := range clients {
+		client.SetReady(false)
+		h.dequeue(client)
+		sendError(client, fmt.Sprintf("The game could not be started: %v", err))
+	}
+}
+
+// minPlayers is the number of players a game of the mode needs at least
+func minPlayers(mode game.Mode) int {
+	if mode.MinPlayers == 0 {
+		return DEFAULT_MATCH_SIZE
+	}
+	return mode.MinPlayers
 }
 
 func (h *Hub) GameFinished(gameID string, result game.GameResult) {
@@ -231,6 +393,18 @@ func (h *Hub) GameFinished(gameID string, result game.GameResult) {
 		return
 	}
 
+	gamesFinished.WithLabelValues(h.gameStarts[gameID].Name).Inc()
+	h.updateGauges()
+	delete(h.gameStarts, gameID)
+	// Players that are still disconnected can come back to the lobby for the
+	// rest of their grace period, their score is kept until then
+	for _, disconnected := range h.disconnected {
+		if disconnected.gameID == gameID {
+			disconnected.gameID = ""
+			delete(h.clientToGame, disconnected.client)
+		}
+	}
+
 	clientsToRemove := []Client{}
 	for client, gid := range h.clientToGame {
 		if gid == gameID {
@@ -240,6 +414,7 @@ func (h *Hub) GameFinished(gameID string, result game.GameResult) {
 
 	for _, client := range clientsToRemove {
 		delete(h.clientToGame, client)
+		delete(h.resumeTokens, client)
 		client.SetGameID("")
 		err := client.SendMessage(message.BackToLobby, nil)
 		if err != nil {
@@ -248,6 +423,21 @@ func (h *Hub) GameFinished(gameID string, result game.GameResult) {
 		log.Info("Client %s removed from finished game %s, returned to lobby.", client.GetID(), gameID)
 	}
 
+	for _, room := range h.rooms {
+		if room.gameID == gameID {
+			room.gameID = ""
+			h.sendRoomUpdate(room)
+		}
+	}
+
+	spectatorsToNotify := []Client{}
+	for spectator, gid := range h.spectators {
+		if gid == gameID {
+			h.spectators[spectator] = ""
+			spectatorsToNotify = append(spectatorsToNotify, spectator)
+		}
+	}
+
 	if len(result.Scores) > 0 {
 		h.updateScoresInternal(result.Scores)
 	}
@@ -255,11 +445,20 @@ func (h *Hub) GameFinished(gameID string, result game.GameResult) {
 	if result.Winner != "" {
 		log.Info("The winner of the game is %s", result.Winner)
 	}
+	if result.WinningTeam != 0 {
+		log.Info("The winning team of the game is team %d", result.WinningTeam)
+	}
 
 	// Using a goroutine to avoid blocking and potential deadlocks
 	go func() {
+		// Spectators of the finished game can pick the next one
+		for _, spectator := range spectatorsToNotify {
+			if err := spectator.SendMessage(message.BackToLobby, nil); err != nil {
+				log.Errorln("Error while trying to send \"BackToLobby\" message to spectator: ", err)
+			}
+			h.sendActiveGames(spectator)
+		}
 		h.broadcastLobbyUpdate()
-		time.AfterFunc(500*time.Millisecond, h.checkAndPotentiallyStartGame)
 	}()
 }
 
@@ -267,11 +466,16 @@ func (h *Hub) broadcastLobbyUpdate() {
 	playerInfos := make(map[string]message.PlayerInfo)
 	h.gameMutex.RLock()
 	for client := range h.clients {
+		// Private rooms are not shown in the public lobby
+		if _, inRoom := h.clientToRoom[client]; inRoom {
+			continue
+		}
 		_, inGame := h.clientToGame[client]
 		playerInfos[client.GetID()] = message.PlayerInfo{
-			InGame:  inGame,
-			IsReady: client.IsReady(),
-			Score:   client.GetScore(),
+			InGame:   inGame,
+			IsReady:  client.IsReady(),
+			Score:    client.GetScore(),
+			GameMode: client.GetGameMode(),
 		}
 	}
 	h.gameMutex.RUnlock()
@@ -297,25 +501,6 @@ func (h *Hub) broadcastMessageInternal(msgType message.MessageType, payload any)
 	}
 }
 
-func (h *Hub) checkAndPotentiallyStartGame() {
-	h.gameMutex.RLock()
-	lobbyClientsCount := 0
-	for c := range h.clients {
-		if _, inGame := h.clientToGame[c]; !inGame {
-			lobbyClientsCount++
-		}
-	}
-	canStart := lobbyClientsCount > 1
-	h.gameMutex.RUnlock()
-
-	if canStart {
-		log.Info("Enough players %d/2 in lobby. Trying to start a game...", lobbyClientsCount)
-		h.selectAndStartGame()
-	} else {
-		log.Warn("Not Enough players %d/2 in lobby. Waiting for more players to join...", lobbyClientsCount)
-	}
-}
-
 func (h *Hub) updateScoresInternal(scores map[string]int) {
 	for clientID, delta := range scores {
 		var targetClient Client
@@ -325,6 +510,12 @@ func (h *Hub) updateScoresInternal(scores map[string]int) {
 				break
 			}
 		}
+		// A disconnected player gets the score once it resumes
+		for _, disconnected := range h.disconnected {
+			if targetClient == nil && disconnected.client.GetID() == clientID {
+				targetClient = disconnected.client
+			}
+		}
 		if targetClient != nil {
 			targetClient.IncrementScore(delta)
 			log.Info("Score updated for %s: new score %d", targetClient.GetID(), targetClient.GetScore())
@@ -0,0 +1,37 @@
+package hub
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	connectedClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bomberman_connected_clients",
+		Help: "Players connected to the live hub",
+	})
+	connectedSpectators = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bomberman_connected_spectators",
+		Help: "Spectators connected to the live hub",
+	})
+	activeGamesCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bomberman_active_games",
+		Help: "Games running right now",
+	})
+	gamesStarted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bomberman_games_started_total",
+		Help: "Games started by game mode",
+	}, []string{"mode"})
+	gamesFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bomberman_games_finished_total",
+		Help: "Games finished by game mode",
+	}, []string{"mode"})
+)
+
+// updateGauges refreshes the gauges after clients or games changed.
+// The caller has to hold the gameMutex.
+func (h *Hub) updateGauges() {
+	connectedClients.Set(float64(len(h.clients)))
+	connectedSpectators.Set(float64(len(h.spectators)))
+	activeGamesCount.Set(float64(len(h.activeGames)))
+}
@@ -5,7 +5,6 @@ import (
 	"sync"
 
 	"github.com/N3moAhead/bombahead/server/internal/game"
-	"github.com/N3moAhead/bombahead/server/internal/game/classic"
 	"github.com/N3moAhead/bombahead/server/internal/message"
 	"github.com/N3moAhead/bombahead/server/pkg/logger"
 	"github.com/google/uuid"
@@ -13,8 +12,8 @@ import (
 
 var log = logger.New("[HUB]")
 
-// OneShotHub is a hub that waits for two players,
-// runs one game, and then shuts down
+// OneShotHub is a hub that waits for as many players as its game mode
+// puts into one game, runs one game, and then shuts down
 type OneShotHub struct {
 	clients         map[Client]bool
 	Register        chan Client
@@ -23,18 +22,20 @@ type OneShotHub struct {
 	game            game.Game
 	gameMutex       sync.Mutex
 	historyFilePath string
+	gameMode        game.Mode
 	shutdown        chan struct{}
 	Done            chan struct{}
 }
 
-// NewOneShotHub creates a new OneShotHub
-func NewOneShotHub(historyFilePath string) *OneShotHub {
+// NewOneShotHub creates a new OneShotHub which will play one game of the given mode
+func NewOneShotHub(historyFilePath string, gameMode game.Mode) *OneShotHub {
 	return &OneShotHub{
 		clients:         make(map[Client]bool),
 		Register:        make(chan Client),
 		unregister:      make(chan Client),
 		incoming:        make(chan hubMessage),
 		historyFilePath: historyFilePath,
+		gameMode:        gameMode,
 		shutdown:        make(chan struct{}),
 		Done:            make(chan struct{}),
 	}
@@ -43,16 +44,21 @@ func NewOneShotHub(historyFilePath string) *OneShotHub {
 // Run starts the hubs main loop
 func (h *OneShotHub) Run() {
 	defer close(h.Done)
-	log.Info("Is running, waiting for 2 players...")
+	log.Info("Is running, waiting for %d players...", h.matchSize())
 	gameStarted := false
 
 	for {
 		select {
 		case client := <-h.Register:
-			if len(h.clients) < 2 {
+			if len(h.clients) < h.matchSize() {
 				h.clients[client] = true
-				log.Info("Client %s registered. Total clients: %d/2", client.GetID(), len(h.clients))
-				welcomePayload := message.WelcomeMessage{ClientID: client.GetID()}
+				log.Info("Client %s registered. Total clients: %d/%d", client.GetID(), len(h.clients), h.matchSize())
+				welcomePayload := message.WelcomeMessage{
+					ClientID:        client.GetID(),
+					CurrentGames:    h.gameInfos(),
+					ProtocolVersion: message.MIN_PROTOCOL_VERSION,
+					Capabilities:    []string{}, // Nothing is negotiated before the hello
+				}
 				err := client.SendMessage(message.Welcome, welcomePayload)
 				if err != nil {
 					log.Errorln("Error while trying to send WelcomeMessage to client", err)
@@ -70,19 +76,36 @@ func (h *OneShotHub) Run() {
 				// The game logic itself handles player disconnection
 			}
 		case hubMsg := <-h.incoming:
-			if hubMsg.message.Type == message.PlayerStatusUpdate {
+			if hubMsg.message.Type == message.Hello {
+				negotiateProtocol(hubMsg.client, hubMsg.message, h.gameInfos())
+			} else if hubMsg.message.Type == message.PlayerStatusUpdate {
 				h.gameMutex.Lock()
 				var payload message.PlayerStatusUpdatePayload
 				if err := json.Unmarshal(hubMsg.message.Payload, &payload); err != nil {
 					log.Error("Error while unmarshalling PlayerStatusUpdate %v\n", err)
 					return
 				}
+				if missing := h.gameMode.MissingCapability(hubMsg.client); missing != "" {
+					h.gameMutex.Unlock()
+					// The one-shot game can't start without this client, so it is told to leave
+					closeReason := "missing capability " + missing
+					log.Warn("Client %s can't play %s without the %s capability", hubMsg.client.GetID(), h.gameMode.Name, missing)
+					err := hubMsg.client.SendMessage(message.Error, message.ErrorMessage{
+						Message:     "The game mode " + h.gameMode.Name + " requires the " + missing + " capability",
+						CloseReason: closeReason,
+					})
+					if err != nil {
+						log.Errorln("Failed to send Error Message to client ", err)
+					}
+					hubMsg.client.CloseWithReason(closeReason)
+					continue
+				}
 				hubMsg.client.SetAuthToken(payload.AuthToken)
 				hubMsg.client.SetReady(true)
 				log.Success("Set auth Token %s for client %s", payload.AuthToken, hubMsg.client.GetID())
 				h.gameMutex.Unlock()
 				if h.canStartGame() && !gameStarted {
-					log.Info("Two players connected and are ready, starting game...")
+					log.Info("All %d players connected and are ready, starting game...", h.matchSize())
 					h.startGame()
 					gameStarted = true
 				}
@@ -106,8 +129,20 @@ func (h *OneShotHub) Run() {
 	}
 }
 
+func (h *OneShotHub) gameInfos() []message.GameInfo {
+	return []message.GameInfo{{Name: h.gameMode.Name, Description: h.gameMode.Description}}
+}
+
+// matchSize is the number of players the game of this hub is played with
+func (h *OneShotHub) matchSize() int {
+	if h.gameMode.MatchSize == 0 {
+		return DEFAULT_MATCH_SIZE
+	}
+	return h.gameMode.MatchSize
+}
+
 func (h *OneShotHub) canStartGame() bool {
-	if len(h.clients) == 2 {
+	if len(h.clients) == h.matchSize() {
 		canStart := true
 		for client := range h.clients {
 			if !client.IsReady() {
@@ -125,20 +160,25 @@ func (h *OneShotHub) startGame() {
 
 	gameID := uuid.New().String()
 	// The OneShotHub implements GameFinisher, so we pass 'h'
-	newGame := classic.NewClassic(h, gameID, h.historyFilePath)
+	newGame := h.gameMode.New(h, gameID, h.historyFilePath)
 	h.game = newGame
 
+	addedClients := []Client{}
 	for client := range h.clients {
 		err := h.game.AddPlayer(client)
 		if err != nil {
 			log.Error("Error adding player %s to game: %v", client.GetID(), err)
 		} else {
 			log.Info("Added player %s to game %s", client.GetID(), gameID)
-			startPayload := message.GameStartPayload{Name: "Classic (One-Shot)", Description: "The classic bomberman game, one-shot style!", GameID: gameID}
-			err := client.SendMessage(message.GameStart, startPayload)
-			if err != nil {
-				log.Errorln("Error while trying to send gamestart payload to client", err)
-			}
+			addedClients = append(addedClients, client)
+		}
+	}
+
+	startPayload := newGameStartPayload(h.gameMode, gameID, newGame)
+	for _, client := range addedClients {
+		err := client.SendMessage(message.GameStart, startPayload)
+		if err != nil {
+			log.Errorln("Error while trying to send gamestart payload to client", err)
 		}
 	}
 	go h.game.Start()
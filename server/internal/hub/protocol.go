@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"encoding/json"
+
+	"github.com/N3moAhead/bombahead/server/internal/game"
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+// negotiateProtocol answers the hello of a client with a welcome message
+// containing the negotiated protocol. Clients speaking an unsupported
+// protocol receive an error and are disconnected
+func negotiateProtocol(client Client, msg message.Message, currentGames []message.GameInfo) {
+	var hello message.HelloPayload
+	if err := json.Unmarshal(msg.Payload, &hello); err != nil {
+		log.Error("Error unmarshalling hello payload from %s: %v", client.GetID(), err)
+		err := client.SendMessage(message.Error, message.ErrorMessage{Message: "Invalid HelloPayload payload"})
+		if err != nil {
+			log.Errorln("Failed to send Error Message to client ", err)
+		}
+		return
+	}
+
+	version, capabilities, err := message.Negotiate(hello)
+	if err != nil {
+		log.Warn("Client %s is incompatible: %v", client.GetID(), err)
+		closeReason := "incompatible protocol version"
+		err := client.SendMessage(message.Error, message.ErrorMessage{Message: err.Error(), CloseReason: closeReason})
+		if err != nil {
+			log.Errorln("Failed to send Error Message to client ", err)
+		}
+		client.CloseWithReason(closeReason)
+		return
+	}
+
+	client.SetProtocol(version, capabilities)
+	log.Info("Client %s negotiated protocol version %d with capabilities %v", client.GetID(), version, capabilities)
+	welcomePayload := message.WelcomeMessage{
+		ClientID:        client.GetID(),
+		CurrentGames:    currentGames,
+		ProtocolVersion: version,
+		Capabilities:    capabilities,
+	}
+	if err := client.SendMessage(message.Welcome, welcomePayload); err != nil {
+		log.Errorln("Failed to send message to client ", err)
+	}
+}
+
+// canPlay reports whether the client negotiated every capability the mode
+// requires. Clients that can't play the mode are told why.
+func canPlay(client Client, mode game.Mode) bool {
+	missing := mode.MissingCapability(client)
+	if missing == "" {
+		return true
+	}
+	log.Warn("Client %s can't play %s without the %s capability", client.GetID(), mode.Name, missing)
+	sendError(client, "The game mode "+mode.Name+" requires the "+missing+" capability")
+	return false
+}
@@ -0,0 +1,104 @@
+package hub
+
+import (
+	"slices"
+
+	"github.com/N3moAhead/bombahead/server/internal/game"
+)
+
+// DEFAULT_MATCH_SIZE is used for game modes that don't set their own match size
+const DEFAULT_MATCH_SIZE = 2
+
+// enqueue puts a ready lobby client at the end of the matchmaking queue.
+// The caller has to hold the gameMutex.
+func (h *Hub) enqueue(client Client) {
+	if !slices.Contains(h.queue, client) {
+		h.queue = append(h.queue, client)
+	}
+}
+
+// dequeue removes a client from the matchmaking queue.
+// The caller has to hold the gameMutex.
+func (h *Hub) dequeue(client Client) {
+	h.queue = slices.DeleteFunc(h.queue, func(queued Client) bool {
+		return queued == client
+	})
+}
+
+// matchmake pulls the queued players into as many games as their modes allow
+// and returns how many games were started. The caller has to hold the gameMutex.
+func (h *Hub) matchmake() int {
+	queuedByMode := make(map[string][]Client) // Mode name -> queued clients in queue order
+	modes := []game.Mode{}
+	for _, client := range h.queue {
+		mode, ok := h.gameModes.Get(client.GetGameMode())
+		if !ok {
+			log.Error("Client %s selected the game mode '%s' which is not registered", client.GetID(), client.GetGameMode())
+			continue
+		}
+		if _, ok := queuedByMode[mode.Name]; !ok {
+			modes = append(modes, mode)
+		}
+		queuedByMode[mode.Name] = append(queuedByMode[mode.Name], client)
+	}
+
+	started := 0
+	for _, mode := range modes {
+		matchSize := mode.MatchSize
+		if matchSize == 0 {
+			matchSize = DEFAULT_MATCH_SIZE
+		}
+		queued := queuedByMode[mode.Name]
+		for len(queued) >= matchSize {
+			var players []Client
+			players, queued = h.pickPlayers(queued, matchSize)
+			if h.startGameForMode(mode, players) == "" {
+				break
+			}
+			started++
+		}
+		if len(queued) > 0 {
+			log.Info("%d players are waiting for a %s game of %d", len(queued), mode.Name, matchSize)
+		}
+	}
+
+	// Players the game could not take stay in the queue for the next match
+	h.queue = slices.DeleteFunc(h.queue, func(client Client) bool {
+		_, inGame := h.clientToGame[client]
+		_, known := h.gameModes.Get(client.GetGameMode())
+		return inGame || !known
+	})
+	return started
+}
+
+// pickPlayers takes the players for the next game out of the queued clients.
+// The longest waiting player always plays, the others are either the next
+// ones in the queue or, when matching by score, the ones closest to its score.
+func (h *Hub) pickPlayers(queued []Client, matchSize int) ([]Client, []Client) {
+	if !h.matchByScore {
+		return queued[:matchSize:matchSize], queued[matchSize:]
+	}
+
+	first := queued[0]
+	opponents := slices.Clone(queued[1:])
+	slices.SortStableFunc(opponents, func(a, b Client) int {
+		return scoreDistance(first, a) - scoreDistance(first, b)
+	})
+	players := append([]Client{first}, opponents[:matchSize-1]...)
+
+	rest := make([]Client, 0, len(queued)-matchSize)
+	for _, client := range queued[1:] {
+		if !slices.Contains(players, client) {
+			rest = append(rest, client)
+		}
+	}
+	return players, rest
+}
+
+func scoreDistance(a, b Client) int {
+	distance := a.GetScore() - b.GetScore()
+	if distance < 0 {
+		return -distance
+	}
+	return distance
+}
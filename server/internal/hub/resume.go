@@ -0,0 +1,171 @@
+package hub
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/N3moAhead/bombahead/server/internal/game"
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+// RESUME_GRACE_PERIOD is how long a player whose connection dropped during a
+// game can reconnect before it is removed from the game
+const RESUME_GRACE_PERIOD = 15 * time.Second
+
+// disconnectedPlayer is a player whose connection dropped during a game
+type disconnectedPlayer struct {
+	client Client      // The client that lost its connection, keeps the score until the player resumes
+	gameID string      // Empty once the game is over, the player then resumes in the lobby
+	timer  *time.Timer // Removes the player from the game when the grace period is over
+}
+
+// holdForResume keeps a client whose connection dropped in its game, so it
+// can resume the game with a new connection during the grace period.
+// Returns false if the client can't resume. The caller has to hold the gameMutex.
+func (h *Hub) holdForResume(client Client, gameID string) bool {
+	token, ok := h.resumeTokens[client]
+	if !ok {
+		return false
+	}
+	activeGame, ok := h.activeGames[gameID]
+	if !ok {
+		return false
+	}
+
+	activeGame.DisconnectPlayer(client)
+	h.disconnected[token] = &disconnectedPlayer{
+		client: client,
+		gameID: gameID,
+		timer: time.AfterFunc(RESUME_GRACE_PERIOD, func() {
+			h.resumeExpired <- token
+		}),
+	}
+	log.Warn("Client %s lost its connection during game %s, it can resume for %s", client.GetID(), gameID, RESUME_GRACE_PERIOD)
+	return true
+}
+
+// expireResume removes a disconnected player from its game once the grace period is over
+func (h *Hub) expireResume(token string) {
+	h.gameMutex.Lock()
+	disconnected, ok := h.disconnected[token]
+	if !ok {
+		// The player resumed in the meantime or the game is already over
+		h.gameMutex.Unlock()
+		return
+	}
+	delete(h.disconnected, token)
+	delete(h.resumeTokens, disconnected.client)
+	delete(h.clientToGame, disconnected.client)
+	h.leaveRoom(disconnected.client)
+	if activeGame, ok := h.activeGames[disconnected.gameID]; ok {
+		activeGame.RemovePlayer(disconnected.client)
+	}
+	log.Warn("Client %s did not resume game %s in time and was removed", disconnected.client.GetID(), disconnected.gameID)
+	h.gameMutex.Unlock()
+
+	h.broadcastLobbyUpdate()
+}
+
+// resumeGame lets a new connection take the place of a disconnected player
+func (h *Hub) resumeGame(client Client, msg message.Message) {
+	var payload message.ResumePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		log.Error("Error unmarshalling resume payload from %s: %v", client.GetID(), err)
+		err := client.SendMessage(message.Error, message.ErrorMessage{Message: "Invalid ResumePayload payload"})
+		if err != nil {
+			log.Errorln("Failed to send Error Message to client ", err)
+		}
+		return
+	}
+
+	h.gameMutex.Lock()
+	disconnected, ok := h.disconnected[payload.ResumeToken]
+	var activeGame game.Game
+	if ok && disconnected.gameID != "" {
+		activeGame, ok = h.activeGames[disconnected.gameID]
+	}
+	if !ok {
+		h.gameMutex.Unlock()
+		log.Warn("Client %s tried to resume with an unknown or expired token", client.GetID())
+		err := client.SendMessage(message.Error, message.ErrorMessage{Message: "Unknown or expired resume token"})
+		if err != nil {
+			log.Errorln("Failed to send Error Message to client ", err)
+		}
+		return
+	}
+
+	disconnected.timer.Stop()
+	delete(h.disconnected, payload.ResumeToken)
+	delete(h.resumeTokens, disconnected.client)
+	delete(h.clientToGame, disconnected.client)
+
+	// A new connection may already have joined a room, it can only be in the room of the game
+	h.leaveRoom(client)
+	temporaryID := client.GetID()
+	client.Resume(disconnected.client)
+	if activeGame == nil {
+		h.resumeInLobby(client, disconnected.client)
+		h.gameMutex.Unlock()
+		log.Success("Client %s resumed as %s after its game was over", temporaryID, client.GetID())
+		h.sendResumeWelcome(client)
+		if err := client.SendMessage(message.BackToLobby, nil); err != nil {
+			log.Errorln("Error while trying to send \"BackToLobby\" message to client: ", err)
+		}
+		h.broadcastLobbyUpdate()
+		return
+	}
+	client.SetGameID(disconnected.gameID)
+	if err := activeGame.ReconnectPlayer(client); err != nil {
+		h.gameMutex.Unlock()
+		log.Error("Client %s could not resume game %s: %v", client.GetID(), disconnected.gameID, err)
+		err := client.SendMessage(message.Error, message.ErrorMessage{Message: "The game can't be resumed"})
+		if err != nil {
+			log.Errorln("Failed to send Error Message to client ", err)
+		}
+		return
+	}
+	h.dequeue(client)
+	h.clientToGame[client] = disconnected.gameID
+	h.resumeTokens[client] = payload.ResumeToken
+	h.replaceInRoom(disconnected.client, client)
+	startPayload := h.gameStarts[disconnected.gameID]
+	startPayload.ResumeToken = payload.ResumeToken
+	h.gameMutex.Unlock()
+
+	log.Success("Client %s resumed game %s as %s", temporaryID, disconnected.gameID, client.GetID())
+
+	// The client learns its old ID again and everything it needs to know about the game
+	h.sendResumeWelcome(client)
+	if err := client.SendMessage(message.GameStart, startPayload); err != nil {
+		log.Errorln("Error while trying to send gameStartPayload to client ", err)
+	}
+	h.broadcastLobbyUpdate()
+}
+
+// resumeInLobby lets a new connection take the place of a player whose game
+// ended while it was disconnected. The caller has to hold the gameMutex.
+func (h *Hub) resumeInLobby(client Client, previous Client) {
+	client.SetGameID("")
+	delete(h.resumeTokens, previous)
+	h.dequeue(client)
+	h.replaceInRoom(previous, client)
+}
+
+// sendResumeWelcome tells a resumed client its old ID and the negotiated protocol
+func (h *Hub) sendResumeWelcome(client Client) {
+	capabilities := []string{}
+	for _, capability := range message.Capabilities {
+		if client.HasCapability(capability) {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	welcomePayload := message.WelcomeMessage{
+		ClientID:        client.GetID(),
+		CurrentGames:    h.gameModes.GameInfos(),
+		ProtocolVersion: client.GetProtocolVersion(),
+		Capabilities:    capabilities,
+	}
+	if err := client.SendMessage(message.Welcome, welcomePayload); err != nil {
+		log.Errorln("Failed to send message to client ", err)
+	}
+}
@@ -0,0 +1,235 @@
+package hub
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+const (
+	ROOM_CODE_LENGTH   = 6
+	ROOM_CODE_ALPHABET = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // Without 0, O, 1 and I which are easily confused
+	MIN_ROOM_PLAYERS   = 2
+	MAX_ROOM_PLAYERS   = 4
+)
+
+// Room is a private lobby only players knowing its join code can enter.
+// It has its own ready check, so nobody outside the room can block its games.
+type Room struct {
+	Code       string
+	Host       Client // Can change the game mode, passed on when the host leaves
+	MaxPlayers int
+	GameMode   string
+	players    []Client // In the order they joined
+	gameID     string   // The running game of the room, empty between games
+}
+
+// newRoomCode generates a join code that is not used by another room.
+// The caller has to hold the gameMutex.
+func (h *Hub) newRoomCode() string {
+	for {
+		// The alphabet has 32 characters, so every byte maps evenly onto it
+		code := make([]byte, ROOM_CODE_LENGTH)
+		_, _ = rand.Read(code)
+		for i, b := range code {
+			code[i] = ROOM_CODE_ALPHABET[int(b)%len(ROOM_CODE_ALPHABET)]
+		}
+		if _, exists := h.rooms[string(code)]; !exists {
+			return string(code)
+		}
+	}
+}
+
+// handleRoomMessage handles creating, joining and leaving rooms
+func (h *Hub) handleRoomMessage(client Client, msg message.Message) {
+	switch msg.Type {
+	case message.CreateRoom:
+		var payload message.CreateRoomPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			sendError(client, "Invalid CreateRoomPayload payload")
+			return
+		}
+		h.createRoom(client, payload)
+	case message.JoinRoom:
+		var payload message.JoinRoomPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			sendError(client, "Invalid JoinRoomPayload payload")
+			return
+		}
+		h.joinRoom(client, payload.Code)
+	case message.LeaveRoom:
+		h.gameMutex.Lock()
+		h.leaveRoom(client)
+		h.gameMutex.Unlock()
+	}
+	h.broadcastLobbyUpdate()
+}
+
+func (h *Hub) createRoom(client Client, payload message.CreateRoomPayload) {
+	if payload.MaxPlayers == 0 {
+		payload.MaxPlayers = MIN_ROOM_PLAYERS
+	}
+	if payload.MaxPlayers < MIN_ROOM_PLAYERS || payload.MaxPlayers > MAX_ROOM_PLAYERS {
+		sendError(client, fmt.Sprintf("Rooms have to allow between %d and %d players", MIN_ROOM_PLAYERS, MAX_ROOM_PLAYERS))
+		return
+	}
+	mode, ok := h.gameModes.Get(payload.GameMode)
+	if !ok {
+		sendError(client, "Unknown game mode "+payload.GameMode)
+		return
+	}
+	if !canPlay(client, mode) {
+		return
+	}
+
+	h.gameMutex.Lock()
+	defer h.gameMutex.Unlock()
+	if _, inRoom := h.clientToRoom[client]; inRoom {
+		sendError(client, "Leave your room before creating a new one")
+		return
+	}
+
+	room := &Room{
+		Code:       h.newRoomCode(),
+		Host:       client,
+		MaxPlayers: payload.MaxPlayers,
+		GameMode:   mode.Name,
+		players:    []Client{client},
+	}
+	h.rooms[room.Code] = room
+	h.clientToRoom[client] = room
+	h.dequeue(client)
+	client.SetReady(false)
+	log.Success("Client %s created the room %s for %s", client.GetID(), room.Code, room.GameMode)
+	h.sendRoomUpdate(room)
+}
+
+func (h *Hub) joinRoom(client Client, code string) {
+	h.gameMutex.Lock()
+	defer h.gameMutex.Unlock()
+
+	room, ok := h.rooms[code]
+	switch {
+	case !ok:
+		sendError(client, "Unknown room code "+code)
+		return
+	case h.clientToRoom[client] != nil:
+		sendError(client, "Leave your room before joining another one")
+		return
+	case len(room.players) >= room.MaxPlayers:
+		sendError(client, "The room "+code+" is full")
+		return
+	case room.gameID != "":
+		sendError(client, "The room "+code+" is playing a game right now")
+		return
+	}
+	if mode, ok := h.gameModes.Get(room.GameMode); ok && !canPlay(client, mode) {
+		return
+	}
+
+	room.players = append(room.players, client)
+	h.clientToRoom[client] = room
+	h.dequeue(client)
+	client.SetReady(false)
+	log.Info("Client %s joined the room %s", client.GetID(), room.Code)
+	h.sendRoomUpdate(room)
+}
+
+// leaveRoom removes the client from its room. Empty rooms are closed.
+// The caller has to hold the gameMutex.
+func (h *Hub) leaveRoom(client Client) {
+	room, ok := h.clientToRoom[client]
+	if !ok {
+		return
+	}
+	delete(h.clientToRoom, client)
+	for i, player := range room.players {
+		if player == client {
+			room.players = append(room.players[:i], room.players[i+1:]...)
+			break
+		}
+	}
+	log.Info("Client %s left the room %s", client.GetID(), room.Code)
+
+	if len(room.players) == 0 {
+		delete(h.rooms, room.Code)
+		log.Info("Room %s is empty and was closed", room.Code)
+		return
+	}
+	if room.Host == client {
+		room.Host = room.players[0]
+	}
+	h.sendRoomUpdate(room)
+}
+
+// replaceInRoom hands the room membership of a client over to the
+// connection that resumed its game. The caller has to hold the gameMutex.
+func (h *Hub) replaceInRoom(previous Client, client Client) {
+	room, ok := h.clientToRoom[previous]
+	if !ok {
+		return
+	}
+	delete(h.clientToRoom, previous)
+	h.clientToRoom[client] = room
+	for i, player := range room.players {
+		if player == previous {
+			room.players[i] = client
+		}
+	}
+	if room.Host == previous {
+		room.Host = client
+	}
+}
+
+// checkRoom starts a game as soon as every player of the room is ready.
+// The caller has to hold the gameMutex.
+func (h *Hub) checkRoom(room *Room) {
+	if room.gameID != "" || len(room.players) < MIN_ROOM_PLAYERS {
+		return
+	}
+	mode, ok := h.gameModes.Get(room.GameMode)
+	if !ok {
+		log.Error("Room %s selected the game mode '%s' which is not registered", room.Code, room.GameMode)
+		return
+	}
+	room.gameID = h.startGameForMode(mode, room.players)
+	if room.gameID != "" {
+		h.sendRoomUpdate(room)
+	}
+}
+
+// sendRoomUpdate tells every player of the room who is in it.
+// The caller has to hold the gameMutex.
+func (h *Hub) sendRoomUpdate(room *Room) {
+	players := make(map[string]message.PlayerInfo, len(room.players))
+	for _, player := range room.players {
+		_, inGame := h.clientToGame[player]
+		players[player.GetID()] = message.PlayerInfo{
+			InGame:   inGame,
+			IsReady:  player.IsReady(),
+			Score:    player.GetScore(),
+			GameMode: room.GameMode,
+		}
+	}
+	payload := message.RoomUpdatePayload{
+		Code:       room.Code,
+		Host:       room.Host.GetID(),
+		MaxPlayers: room.MaxPlayers,
+		GameMode:   room.GameMode,
+		InGame:     room.gameID != "",
+		Players:    players,
+	}
+	for _, player := range room.players {
+		if err := player.SendMessage(message.RoomUpdate, payload); err != nil {
+			log.Error("Error sending the room update to client %s: %v", player.GetID(), err)
+		}
+	}
+}
+
+func sendError(client Client, text string) {
+	if err := client.SendMessage(message.Error, message.ErrorMessage{Message: text}); err != nil {
+		log.Errorln("Failed to send Error Message to client ", err)
+	}
+}
@@ -0,0 +1,117 @@
+package hub
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/N3moAhead/bombahead/server/internal/message"
+)
+
+// handleSpectatorMessage handles the messages of a spectator. Spectators
+// can only look at games, so everything else they send is ignored.
+func (h *Hub) handleSpectatorMessage(spectator Client, msg message.Message) {
+	switch msg.Type {
+	case message.Hello:
+		negotiateProtocol(spectator, msg, h.gameModes.GameInfos())
+	case message.ListGames:
+		h.sendActiveGames(spectator)
+	case message.Spectate:
+		var payload message.SpectatePayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			log.Error("Error unmarshalling spectate payload from %s: %v", spectator.GetID(), err)
+			err := spectator.SendMessage(message.Error, message.ErrorMessage{Message: "Invalid SpectatePayload payload"})
+			if err != nil {
+				log.Errorln("Failed to send Error Message to client ", err)
+			}
+			return
+		}
+		h.spectate(spectator, payload.GameID, payload.Code)
+	default:
+		log.Warn("Ignoring message type '%s' from spectator %s", msg.Type, spectator.GetID())
+	}
+}
+
+// spectate lets the spectator watch the game. It stops watching the
+// game it watched before. An empty game ID only stops watching.
+// Games of private rooms can only be watched with the room's join code.
+func (h *Hub) spectate(spectator Client, gameID string, code string) {
+	h.gameMutex.Lock()
+	if watchedGame, ok := h.activeGames[h.spectators[spectator]]; ok {
+		watchedGame.RemoveSpectator(spectator)
+	}
+	h.spectators[spectator] = ""
+	if gameID == "" {
+		h.gameMutex.Unlock()
+		return
+	}
+
+	activeGame, ok := h.activeGames[gameID]
+	// Without the right code a room game looks like any game that does not exist
+	if room := h.roomOfGame(gameID); room != nil && room.Code != code {
+		ok = false
+	}
+	if !ok {
+		h.gameMutex.Unlock()
+		err := spectator.SendMessage(message.Error, message.ErrorMessage{Message: "Unknown game " + gameID})
+		if err != nil {
+			log.Errorln("Failed to send Error Message to client ", err)
+		}
+		return
+	}
+	defer h.gameMutex.Unlock()
+	h.spectators[spectator] = gameID
+
+	log.Info("Spectator %s is watching game %s", spectator.GetID(), gameID)
+	// The start message tells the spectator how the game is played before the first state arrives
+	if err := spectator.SendMessage(message.GameStart, h.gameStarts[gameID]); err != nil {
+		log.Errorln("Error while trying to send gameStartPayload to spectator ", err)
+	}
+	activeGame.AddSpectator(spectator)
+}
+
+// sendActiveGames sends the list of running games to the spectator
+func (h *Hub) sendActiveGames(spectator Client) {
+	if err := spectator.SendMessage(message.ActiveGames, h.activeGamesPayload()); err != nil {
+		log.Errorln("Failed to send active games to spectator ", err)
+	}
+}
+
+// activeGamesPayload lists every running game with its players
+func (h *Hub) activeGamesPayload() message.ActiveGamesPayload {
+	h.gameMutex.RLock()
+	defer h.gameMutex.RUnlock()
+
+	players := make(map[string][]string, len(h.activeGames))
+	for client, gameID := range h.clientToGame {
+		players[gameID] = append(players[gameID], client.GetID())
+	}
+
+	payload := message.ActiveGamesPayload{Games: []message.ActiveGameInfo{}}
+	for gameID := range h.activeGames {
+		// Games of private rooms are not listed publicly
+		if h.roomOfGame(gameID) != nil {
+			continue
+		}
+		sort.Strings(players[gameID])
+		payload.Games = append(payload.Games, message.ActiveGameInfo{
+			GameID:  gameID,
+			Name:    h.gameStarts[gameID].Name,
+			Players: players[gameID],
+		})
+	}
+	sort.Slice(payload.Games, func(i, j int) bool {
+		return payload.Games[i].GameID < payload.Games[j].GameID
+	})
+	return payload
+}
+
+// roomOfGame returns the room playing the game, or nil for public games.
+// The caller has to hold the gameMutex.
+func (h *Hub) roomOfGame(gameID string) *Room {
+	for _, room := range h.rooms {
+		if room.gameID == gameID {
+			return room
+		}
+	}
+	return nil
+}
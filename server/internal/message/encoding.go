@@ -0,0 +1,62 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// EncodeJSON serializes a message for a websocket text frame
+func EncodeJSON(msgType MessageType, payload any) ([]byte, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Message{
+		Type:    msgType,
+		Payload: json.RawMessage(payloadBytes),
+	})
+}
+
+// EncodeMsgpack serializes a message for a websocket binary frame.
+// The payload keeps the field names of its json tags, so both
+// encodings describe the same messages.
+func EncodeMsgpack(msgType MessageType, payload any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	err := enc.Encode(map[string]any{
+		"type":    msgType,
+		"payload": payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeMsgpack reads a message from a websocket binary frame. The payload
+// is converted to JSON so handlers don't care which encoding the client uses.
+func DecodeMsgpack(data []byte) (Message, error) {
+	var envelope struct {
+		Type    MessageType        `msgpack:"type"`
+		Payload msgpack.RawMessage `msgpack:"payload"`
+	}
+	if err := msgpack.Unmarshal(data, &envelope); err != nil {
+		return Message{}, err
+	}
+
+	var payload any
+	if len(envelope.Payload) > 0 {
+		if err := msgpack.Unmarshal(envelope.Payload, &payload); err != nil {
+			return Message{}, err
+		}
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Type: envelope.Type, Payload: json.RawMessage(payloadBytes)}, nil
+}
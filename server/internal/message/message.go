@@ -22,24 +22,74 @@ const (
 	Error              MessageType = "error"
 	ClassicInput       MessageType = "classic_input"
 	ClassicState       MessageType = "classic_state"
+	ClassicStateDelta  MessageType = "classic_state_delta" // Changes since the last state, only for clients with the delta_state capability
 	GameStart          MessageType = "game_start"
+	Hello              MessageType = "hello"        // Sent by a client to negotiate the protocol version
+	Resume             MessageType = "resume"       // Sent by a reconnecting client to take its place in a running game again
+	ListGames          MessageType = "list_games"   // Sent by a spectator to get the running games
+	ActiveGames        MessageType = "active_games" // Sent to spectators with the running games
+	Spectate           MessageType = "spectate"     // Sent by a spectator to watch a running game
+	CreateRoom         MessageType = "create_room"  // Sent by a client to open a private room
+	JoinRoom           MessageType = "join_room"    // Sent by a client to enter a private room with its join code
+	LeaveRoom          MessageType = "leave_room"   // Sent by a client to return to the public lobby
+	RoomUpdate         MessageType = "room_update"  // Sent to every player of a room when it changes
+	Notice             MessageType = "notice"       // Sent to every client when an operator broadcasts a notice
 )
 
+var knownTypes = map[MessageType]bool{
+	Welcome:            true,
+	BackToLobby:        true,
+	UpdateLobby:        true,
+	PlayerStatusUpdate: true,
+	Error:              true,
+	ClassicInput:       true,
+	ClassicState:       true,
+	ClassicStateDelta:  true,
+	GameStart:          true,
+	Hello:              true,
+	Resume:             true,
+	ListGames:          true,
+	ActiveGames:        true,
+	Spectate:           true,
+	CreateRoom:         true,
+	JoinRoom:           true,
+	LeaveRoom:          true,
+	RoomUpdate:         true,
+	Notice:             true,
+}
+
+// IsKnown reports whether the message type is part of the protocol
+func (t MessageType) IsKnown() bool {
+	return knownTypes[t]
+}
+
 type GameInfo struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
 
-// WelcomeMessage contains the ID of the new client and the list of available games
+// WelcomeMessage contains the ID of the new client and the list of available games.
+// It always describes the protocol the connection speaks at that moment, so the
+// first one has the base version without capabilities. It is sent again with
+// the negotiated protocol once a client said hello
 type WelcomeMessage struct {
-	ClientID     string     `json:"clientId"`
-	CurrentGames []GameInfo `json:"currentGames"`
+	ClientID        string     `json:"clientId"`
+	CurrentGames    []GameInfo `json:"currentGames"`
+	ProtocolVersion int        `json:"protocolVersion"`
+	Capabilities    []string   `json:"capabilities"`
+}
+
+// HelloPayload declares the protocol version and capabilities of a client
+type HelloPayload struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Capabilities    []string `json:"capabilities"`
 }
 
 type PlayerInfo struct {
-	InGame  bool `json:"inGame"`
-	IsReady bool `json:"isReady"`
-	Score   int  `json:"score"`
+	InGame   bool   `json:"inGame"`
+	IsReady  bool   `json:"isReady"`
+	Score    int    `json:"score"`
+	GameMode string `json:"gameMode"`
 }
 
 // LobbyUpdateMessage contains the current state of the lobby
@@ -50,15 +100,68 @@ type LobbyUpdateMessage struct {
 type PlayerStatusUpdatePayload struct {
 	IsReady   bool   `json:"isReady"`
 	AuthToken string `json:"authToken"`
+	GameMode  string `json:"gameMode,omitempty"` // Name of the game mode the player wants to play, empty for the default mode
 }
 
 type GameStartPayload struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	GameID      string `json:"gameId"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	GameID      string         `json:"gameId"`
+	Config      any            `json:"config,omitempty"`      // Game specific configuration so bots can adapt to it
+	Teams       map[string]int `json:"teams,omitempty"`       // PlayerID -> Team, only set if the game is played in teams
+	ResumeToken string         `json:"resumeToken,omitempty"` // Lets the player resume the game after the connection dropped
+}
+
+// ResumePayload is sent by a client which lost its connection during a game
+type ResumePayload struct {
+	ResumeToken string `json:"resumeToken"`
+}
+
+// CreateRoomPayload configures a new private room
+type CreateRoomPayload struct {
+	MaxPlayers int    `json:"maxPlayers"`         // Between 2 and 4, defaults to 2
+	GameMode   string `json:"gameMode,omitempty"` // Empty for the default game mode
+}
+
+type JoinRoomPayload struct {
+	Code string `json:"code"`
+}
+
+// RoomUpdatePayload contains the current state of a private room
+type RoomUpdatePayload struct {
+	Code       string                `json:"code"`
+	Host       string                `json:"host"` // Client ID of the host
+	MaxPlayers int                   `json:"maxPlayers"`
+	GameMode   string                `json:"gameMode"`
+	InGame     bool                  `json:"inGame"`
+	Players    map[string]PlayerInfo `json:"players"` // Maps client id to client infos
+}
+
+// NoticePayload contains a notice of the server operators
+type NoticePayload struct {
+	Message string `json:"message"`
+}
+
+// ActiveGameInfo describes a running game a spectator can watch
+type ActiveGameInfo struct {
+	GameID  string   `json:"gameId"`
+	Name    string   `json:"name"`
+	Players []string `json:"players"` // IDs of the players in the game
+}
+
+// ActiveGamesPayload contains every game that is currently running
+type ActiveGamesPayload struct {
+	Games []ActiveGameInfo `json:"games"`
+}
+
+// SpectatePayload is sent by a spectator to watch a game, an empty GameID stops watching
+type SpectatePayload struct {
+	GameID string `json:"gameId"`
+	Code   string `json:"code,omitempty"` // Join code of the room, games of private rooms can't be watched without it
 }
 
 // ErrorMessage is sent in case of errors
 type ErrorMessage struct {
-	Message string `json:"message"`
+	Message     string `json:"message"`
+	CloseReason string `json:"closeReason,omitempty"` // Set if the server closes the connection after this error
 }
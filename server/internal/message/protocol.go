@@ -0,0 +1,46 @@
+package message
+
+import "fmt"
+
+// Protocol versions understood by this server. Version 1 is the protocol
+// spoken by clients that never send a Hello message.
+const (
+	PROTOCOL_VERSION     = 2
+	MIN_PROTOCOL_VERSION = 1
+)
+
+// Capabilities a client can declare in its Hello message. Only the
+// capabilities supported by both sides are enabled for a connection.
+const (
+	CAPABILITY_FOG_OF_WAR  = "fog_of_war"  // Understands FOG tiles, required to play fog of war modes
+	CAPABILITY_DELTA_STATE = "delta_state" // States are sent as deltas between keyframes
+	CAPABILITY_MSGPACK     = "msgpack"     // Messages are sent as MessagePack in binary frames
+)
+
+// Capabilities lists every capability supported by the server
+var Capabilities = []string{
+	CAPABILITY_FOG_OF_WAR,
+	CAPABILITY_DELTA_STATE,
+	CAPABILITY_MSGPACK,
+}
+
+// Negotiate picks the protocol version and capabilities used for a client
+// that sent the given hello. An error is returned if the client speaks a
+// protocol version the server does not support.
+func Negotiate(hello HelloPayload) (int, []string, error) {
+	if hello.ProtocolVersion < MIN_PROTOCOL_VERSION {
+		return 0, nil, fmt.Errorf("protocol version %d is no longer supported, minimum is %d", hello.ProtocolVersion, MIN_PROTOCOL_VERSION)
+	}
+	version := min(hello.ProtocolVersion, PROTOCOL_VERSION)
+
+	capabilities := []string{}
+	for _, capability := range Capabilities {
+		for _, requested := range hello.Capabilities {
+			if requested == capability {
+				capabilities = append(capabilities, capability)
+				break
+			}
+		}
+	}
+	return version, capabilities, nil
+}
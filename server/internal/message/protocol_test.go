@@ -0,0 +1,84 @@
+package message
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name             string
+		hello            HelloPayload
+		wantErr          bool
+		wantVersion      int
+		wantCapabilities []string
+	}{
+		{
+			name:    "version 0",
+			hello:   HelloPayload{ProtocolVersion: 0},
+			wantErr: true,
+		},
+		{
+			name:    "below the minimum",
+			hello:   HelloPayload{ProtocolVersion: MIN_PROTOCOL_VERSION - 1, Capabilities: []string{CAPABILITY_FOG_OF_WAR}},
+			wantErr: true,
+		},
+		{
+			name:             "minimum version",
+			hello:            HelloPayload{ProtocolVersion: MIN_PROTOCOL_VERSION},
+			wantVersion:      MIN_PROTOCOL_VERSION,
+			wantCapabilities: []string{},
+		},
+		{
+			name:             "current version",
+			hello:            HelloPayload{ProtocolVersion: PROTOCOL_VERSION},
+			wantVersion:      PROTOCOL_VERSION,
+			wantCapabilities: []string{},
+		},
+		{
+			name:             "newer client",
+			hello:            HelloPayload{ProtocolVersion: PROTOCOL_VERSION + 1},
+			wantVersion:      PROTOCOL_VERSION,
+			wantCapabilities: []string{},
+		},
+		{
+			name:             "unknown capabilities are dropped",
+			hello:            HelloPayload{ProtocolVersion: PROTOCOL_VERSION, Capabilities: []string{"teleport", CAPABILITY_FOG_OF_WAR}},
+			wantVersion:      PROTOCOL_VERSION,
+			wantCapabilities: []string{CAPABILITY_FOG_OF_WAR},
+		},
+		{
+			name:             "capabilities in server order",
+			hello:            HelloPayload{ProtocolVersion: PROTOCOL_VERSION, Capabilities: []string{CAPABILITY_MSGPACK, CAPABILITY_DELTA_STATE, CAPABILITY_FOG_OF_WAR}},
+			wantVersion:      PROTOCOL_VERSION,
+			wantCapabilities: []string{CAPABILITY_FOG_OF_WAR, CAPABILITY_DELTA_STATE, CAPABILITY_MSGPACK},
+		},
+		{
+			name:             "duplicate capabilities",
+			hello:            HelloPayload{ProtocolVersion: PROTOCOL_VERSION, Capabilities: []string{CAPABILITY_DELTA_STATE, CAPABILITY_DELTA_STATE}},
+			wantVersion:      PROTOCOL_VERSION,
+			wantCapabilities: []string{CAPABILITY_DELTA_STATE},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, capabilities, err := Negotiate(tt.hello)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, negotiated version %d", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("negotiated version %d, expected %d", version, tt.wantVersion)
+			}
+			if !slices.Equal(capabilities, tt.wantCapabilities) {
+				t.Errorf("negotiated capabilities %v, expected %v", capabilities, tt.wantCapabilities)
+			}
+		})
+	}
+}
@@ -50,3 +50,17 @@ func (v Vec2) LengthSq() int {
 func (v Vec2) Dot(other Vec2) int {
 	return v.X*other.X + v.Y*other.Y
 }
+
+// ManhattanDist returns the Manhattan distance between v and other (|dx| + |dy|).
+// This is the number of steps needed on a grid without diagonal moves.
+func (v Vec2) ManhattanDist(other Vec2) int {
+	d := v.Sub(other)
+	return abs(d.X) + abs(d.Y)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
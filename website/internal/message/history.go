@@ -14,16 +14,23 @@ type PlayerMove string
 
 // PlayerState represents the state of a player at a certain point
 type PlayerState struct {
-	ID     string `json:"id"`
-	Pos    Vec2   `json:"pos"`
-	Health int    `json:"health"`
-	Score  int    `json:"score"`
+	ID           string `json:"id"`
+	Pos          Vec2   `json:"pos"`
+	Health       int    `json:"health"`
+	Score        int    `json:"score"`
+	MaxBombs     int    `json:"maxBombs"`
+	BlastRadius  int    `json:"blastRadius"`
+	CanPassBombs bool   `json:"canPassBombs"`
+	CanKickBombs bool   `json:"canKickBombs"`
+	HasShield    bool   `json:"hasShield"`
+	Team         int    `json:"team,omitempty"`
 }
 
 // PlayerHistoryEntry represents the state of a player and their move for a single tick
 type PlayerHistoryEntry struct {
 	PlayerState
 	Move      PlayerMove `json:"move"`
+	Bomb      bool       `json:"bomb,omitempty"`
 	AuthToken string     `json:"authToken"`
 }
 
@@ -40,17 +47,88 @@ type BombState struct {
 	Fuse int  `json:"fuse"`
 }
 
+// PowerUpType represents the kind of a power-up
+type PowerUpType string
+
+// PowerUpState represents a power-up lying on the field
+type PowerUpState struct {
+	Pos  Vec2        `json:"pos"`
+	Type PowerUpType `json:"type"`
+}
+
+// GameEventType represents the kind of a game event
+type GameEventType string
+
+// GameEvent represents something that happened during a tick
+type GameEvent struct {
+	Type        GameEventType `json:"type"`
+	Pos         Vec2          `json:"pos"`
+	PlayerID    string        `json:"playerId,omitempty"`
+	OwnerID     string        `json:"ownerId,omitempty"`
+	ChainParent *Vec2         `json:"chainParent,omitempty"`
+	Health      int           `json:"health,omitempty"`
+	PowerUp     PowerUpType   `json:"powerUp,omitempty"`
+}
+
 // TickState represents the dynamic state of the game at a single tick for history purposes
 type TickState struct {
 	Players        []PlayerHistoryEntry `json:"players"`
 	Bombs          []BombState          `json:"bombs"`
 	Explosions     []Vec2               `json:"explosions"`
+	PowerUps       []PowerUpState       `json:"power_ups"`
 	DestroyedBoxes []Vec2               `json:"destroyed_boxes,omitempty"`
+	ClosedWalls    []Vec2               `json:"closed_walls,omitempty"`
+	Events         []GameEvent          `json:"events,omitempty"`
+	Visible        map[string][]int     `json:"visible,omitempty"`      // AuthToken (player ID without one) -> indices of the tiles the player saw
+	Latencies      map[string]int       `json:"latencies_ms,omitempty"` // AuthToken (player ID without one) -> Time the player needed to answer the previous state
+}
+
+// GameConfig represents the configuration a game was played with
+type GameConfig struct {
+	FieldWidth               int     `json:"fieldWidth"`
+	FieldHeight              int     `json:"fieldHeight"`
+	BoxSpawnRate             float64 `json:"boxSpawnRate"`
+	PowerUpSpawnRate         float64 `json:"powerUpSpawnRate"`
+	FuseTicks                int     `json:"fuseTicks"`
+	BombExplosionRadius      int     `json:"bombExplosionRadius"`
+	TickRateMs               int     `json:"tickRateMs"`
+	MaxGameTimeMs            int     `json:"maxGameTimeMs"`
+	WinScorePoints           int     `json:"winScorePoints"`
+	BoxScorePoints           int     `json:"boxScorePoints"`
+	DamageScorePoints        int     `json:"damageScorePoints"`
+	KillScorePoints          int     `json:"killScorePoints"`
+	SurvivalScorePoints      int     `json:"survivalScorePoints"`
+	SurvivalScoreTicks       int     `json:"survivalScoreTicks"`
+	SuddenDeathTicks         int     `json:"suddenDeathTicks"`
+	SuddenDeathIntervalTicks int     `json:"suddenDeathIntervalTicks"`
+	Teams                    int     `json:"teams"`
+	FriendlyFire             bool    `json:"friendlyFire"`
+	ViewRadius               int     `json:"viewRadius"`
+	KeyframeTicks            int     `json:"keyframeTicks"`
+	InitialHealth            int     `json:"initialHealth"`
+	InitialMaxBombs          int     `json:"initialMaxBombs"`
+}
+
+// InputStats summarizes how timely a player sent its inputs
+type InputStats struct {
+	Inputs       int `json:"inputs"`
+	MissedTicks  int `json:"missedTicks"`
+	StaleInputs  int `json:"staleInputs"`
+	FutureInputs int `json:"futureInputs"`
+	AvgLatencyMs int `json:"avgLatencyMs"`
+	MaxLatencyMs int `json:"maxLatencyMs"`
 }
 
 // GameHistory encapsulates the entire history of a game
 type GameHistory struct {
-	InitialField    FieldState  `json:"initial_field"`
-	Ticks           []TickState `json:"ticks"`
-	WinnerAuthToken string      `json:"winnerAuthToken"`
+	Seed            int64                 `json:"seed"`
+	Config          GameConfig            `json:"config"`
+	MapName         string                `json:"map_name"`
+	InitialField    FieldState            `json:"initial_field"`
+	InitialPlayers  []PlayerState         `json:"initial_players"`
+	Ticks           []TickState           `json:"ticks"`
+	WinnerAuthToken string                `json:"winnerAuthToken"`
+	WinningTeam     int                   `json:"winningTeam,omitempty"`
+	Scores          map[string]int        `json:"scores"`                // Final score by auth token
+	InputStats      map[string]InputStats `json:"input_stats,omitempty"` // Timing of the inputs by auth token, player ID without one
 }
@@ -86,14 +86,14 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</span> <button id=\"next-tick\" class=\"btn btn-primary\">Next</button></div></div><div class=\"w-1/3\"><h3 class=\"text-lg font-bold mb-2\">Moves</h3><div class=\"h-[600px] overflow-y-auto border border-gray-300 rounded-lg bg-base-200\"><table class=\"table table-sm\"><thead><tr><th>Tick</th><th>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</span> <button id=\"next-tick\" class=\"btn btn-primary\">Next</button> <select id=\"view-select\" class=\"select select-bordered select-sm hidden\"><option value=\"\">Full view</option></select></div><div id=\"input-stats\" class=\"mt-4 text-sm\"></div></div><div class=\"w-1/3\"><h3 class=\"text-lg font-bold mb-2\">Moves</h3><div class=\"h-[600px] overflow-y-auto border border-gray-300 rounded-lg bg-base-200\"><table class=\"table table-sm\"><thead><tr><th>Tick</th><th>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			var templ_7745c5c3_Var6 string
 			templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(vm.Match.Bot1.Name)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 37, Col: 35}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 41, Col: 35}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 			if templ_7745c5c3_Err != nil {
@@ -106,7 +106,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			var templ_7745c5c3_Var7 string
 			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(vm.Match.Bot2.Name)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 38, Col: 35}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 42, Col: 35}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
 			if templ_7745c5c3_Err != nil {
@@ -119,7 +119,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			var templ_7745c5c3_Var8 string
 			templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(historyJson))
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 53, Col: 85}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 57, Col: 85}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 			if templ_7745c5c3_Err != nil {
@@ -131,7 +131,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var9, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot1.Name)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 60, Col: 45}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 64, Col: 45}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var9)
 			if templ_7745c5c3_Err != nil {
@@ -143,7 +143,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var10, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot2.Name)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 61, Col: 45}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 65, Col: 45}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var10)
 			if templ_7745c5c3_Err != nil {
@@ -155,7 +155,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var11, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot1AuthToken)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 63, Col: 34}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 67, Col: 34}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var11)
 			if templ_7745c5c3_Err != nil {
@@ -167,7 +167,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var12, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot1.Name)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 63, Col: 61}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 67, Col: 61}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var12)
 			if templ_7745c5c3_Err != nil {
@@ -179,7 +179,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var13, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot2AuthToken)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 64, Col: 34}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 68, Col: 34}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var13)
 			if templ_7745c5c3_Err != nil {
@@ -191,7 +191,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var14, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot2.Name)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 64, Col: 61}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 68, Col: 61}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var14)
 			if templ_7745c5c3_Err != nil {
@@ -203,7 +203,7 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var15, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot1AuthToken)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 93, Col: 49}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 97, Col: 49}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var15)
 			if templ_7745c5c3_Err != nil {
@@ -215,13 +215,13 @@ func Details(csrf string, user *models.User, vm *viewmodels.MatchDetail, history
 			}
 			templ_7745c5c3_Var16, templ_7745c5c3_Err := templruntime.ScriptContentInsideStringLiteral(vm.Match.Bot2AuthToken)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 94, Col: 49}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/matches/details.templ`, Line: 98, Col: 49}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var16)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "\";\n\n        history.ticks.forEach((tick, index) => {\n          const moveEntry = document.createElement(\"tr\");\n          moveEntry.classList.add(\"hover\"); // DaisyUI class for hover effect\n          moveEntry.dataset.tick = index;\n\n          const prevTick = index > 0 ? history.ticks[index - 1] : null;\n          const moves = {};\n\n          if (tick.players) {\n            tick.players.forEach((player) => {\n              const prevPlayer = prevTick ? prevTick.players.find(p => p.id === player.id) : null;\n              let move = \"\";\n              // Check for death\n              if (prevPlayer && prevPlayer.health > 0 && player.health === 0) {\n                  move = '💀';\n              }\n              // If not dead, show the move\n              else if (player.move) {\n                  move = player.move;\n              }\n              moves[player.authToken] = move;\n            });\n          }\n\n          const player1Move = moves[bot1Id] || \"\";\n          const player2Move = moves[bot2Id] || \"\";\n\n          const numBombs = tick.bombs ? tick.bombs.length : 0;\n          const numExplosions = tick.explosions ? tick.explosions.length : 0;\n\n          let livesLost = 0;\n          if (index > 0) {\n            const prevTick = history.ticks[index - 1];\n            if (tick.players && prevTick.players) {\n                tick.players.forEach(currentPlayer => {\n                    const prevPlayer = prevTick.players.find(p => p.id === currentPlayer.id);\n                    if (prevPlayer && currentPlayer.health < prevPlayer.health) {\n                        livesLost += (prevPlayer.health - currentPlayer.health);\n                    }\n                });\n            }\n          }\n\n          let eventsStr = \"\";\n          if (livesLost > 0) {\n              eventsStr += `${livesLost}💔 `;\n          }\n          if (numBombs > 0) {\n              eventsStr += `${numBombs}💣 `;\n          }\n          if (numExplosions > 0) {\n              eventsStr += `${numExplosions}💥`;\n          }\n\n          moveEntry.innerHTML = `\n            <th>${index}</th>\n            <td><span class=\"font-mono\">${player1Move}</span></td>\n            <td><span class=\"font-mono\">${player2Move}</span></td>\n            <td>${eventsStr}</td>\n          `;\n\n          moveEntry.addEventListener(\"click\", () => {\n            renderTick(index);\n          });\n          moveListTbody.appendChild(moveEntry);\n        });\n      }\n\n      function isMoveEntryVisible(moveEntry, container) {\n        if (!moveEntry || !container) return false;\n        const entryRect = moveEntry.getBoundingClientRect();\n        const containerRect = container.getBoundingClientRect();\n        return (\n          entryRect.top >= containerRect.top &&\n          entryRect.bottom <= containerRect.bottom\n        );\n      }\n\n      function updateMoveHighlight(tickIndex, shouldScroll = false) {\n        const moveEntries = moveListTbody.children;\n        const moveListContainer = moveListTbody.closest(\".overflow-y-auto\");\n        for (let i = 0; i < moveEntries.length; i++) {\n          if (parseInt(moveEntries[i].dataset.tick) === tickIndex) {\n            moveEntries[i].classList.add(\"active\", \"outline\", \"outline-2\", \"outline-primary\");\n            if (\n              shouldScroll &&\n              moveListContainer &&\n              !isMoveEntryVisible(moveEntries[i], moveListContainer)\n            ) {\n              moveEntries[i].scrollIntoView({ block: \"center\", behavior: \"smooth\" });\n            }\n          } else {\n            moveEntries[i].classList.remove(\"active\", \"outline\", \"outline-2\", \"outline-primary\");\n          }\n        }\n      }\n\n      const tileColors = {\n        AIR: \"lightgray\", // Empty\n        WALL: \"gray\", // Wall\n        BOX: \"sandybrown\", // Box\n      };\n\n      function drawField(field) {\n        for (let y = 0; y < fieldHeight; y++) {\n          for (let x = 0; x < fieldWidth; x++) {\n            const tile = field[y * fieldWidth + x];\n            const destX = x * tileWidth;\n            const destY = y * tileHeight;\n            const baseSpriteWidth = 32;\n            const baseSpriteHeight = 32;\n\n            // Always draw floor first\n            ctx.drawImage(\n              textureAtlas,\n              64, // floor sx\n              0, // floor sy\n              baseSpriteWidth,\n              baseSpriteHeight,\n              destX,\n              destY,\n              tileWidth,\n              tileHeight,\n            );\n\n            if (tile === \"WALL\") {\n              const hasWallUp =\n                y > 0 && field[(y - 1) * fieldWidth + x] === \"WALL\";\n              const hasWallDown =\n                y < fieldHeight - 1 &&\n                field[(y + 1) * fieldWidth + x] === \"WALL\";\n              const hasWallLeft =\n                x > 0 && field[y * fieldWidth + (x - 1)] === \"WALL\";\n              const hasWallRight =\n                x < fieldWidth - 1 &&\n                field[y * fieldWidth + (x + 1)] === \"WALL\";\n\n              let sx = 0;\n              const sy = 32; // Wall sprites are in the second row\n\n              // The logic to select the correct wall sprite based on neighbors.\n              // Bitmask: 8 (Up), 4 (Down), 2 (Left), 1 (Right)\n              const neighbors =\n                (hasWallUp << 3) |\n                (hasWallDown << 2) |\n                (hasWallLeft << 1) |\n                hasWallRight;\n\n              switch (neighbors) {\n                case 0: // No neighbors: solitary wall\n                  sx = 192;\n                  break;\n                case 1: // Right only\n                case 2: // Left only\n                case 3: // Left and Right: horizontal wall\n                  sx = 0;\n                  break;\n                case 4: // Down only\n                case 8: // Up only\n                case 12: // Up and Down: vertical wall\n                  sx = 32;\n                  break;\n                case 5: // Down and Right: corner ╔\n                  sx = 64;\n                  break;\n                case 6: // Down and Left: corner ╗\n                  sx = 96;\n                  break;\n                case 9: // Up and Right: corner ╚\n                  sx = 128;\n                  break;\n                case 10: // Up and Left: corner ╝\n                  sx = 160;\n                  break;\n                default:\n                  // T-junctions and Crosses\n                  if (neighbors & 3) {\n                    // Has Left or Right, prioritize horizontal\n                    sx = 0;\n                  } else {\n                    // Must be a T-junction pointing left/right, use vertical\n                    sx = 32;\n                  }\n                  break;\n              }\n\n              ctx.drawImage(\n                textureAtlas,\n                sx,\n                sy,\n                baseSpriteWidth,\n                baseSpriteHeight,\n                destX,\n                destY,\n                tileWidth,\n                tileHeight,\n              );\n            } else if (tile === \"BOX\") {\n              ctx.drawImage(\n                textureAtlas,\n                32, // sx\n                0, // sy\n                baseSpriteWidth,\n                baseSpriteHeight,\n                destX,\n                destY,\n                tileWidth,\n                tileHeight,\n              );\n            }\n            // For AIR tiles, the floor is already drawn, so do nothing else.\n          }\n        }\n      }\n\n      function drawPlayers(players) {\n        const MAX_LIVES = 3;\n        const HEART_SPRITE_WIDTH = 16;\n        const HEART_SPRITE_HEIGHT = 16;\n        const FULL_HEART_SX = 96;\n        const EMPTY_HEART_SX = 112;\n        const HEARTS_SY = 0;\n        const SPRITE_WIDTH = 32;\n\n        players.forEach((player) => {\n          // Determine player state\n          let state = \"IDLE\";\n          if (player.health === 0) {\n            state = \"DEAD\";\n          } else if (currentTick > 0) {\n            const prevTick = history.ticks[currentTick - 1];\n            const prevPlayer = prevTick.players.find((p) => p.id === player.id);\n            if (prevPlayer) {\n              if (player.pos.x > prevPlayer.pos.x) state = \"RIGHT\";\n              else if (player.pos.x < prevPlayer.pos.x) state = \"LEFT\";\n              else if (player.pos.y > prevPlayer.pos.y) state = \"DOWN\";\n              else if (player.pos.y < prevPlayer.pos.y) state = \"UP\";\n            }\n          }\n\n          // Determine sprite coordinates\n          const playerIndex = playerIndexMap.get(player.id) || 0;\n          const sy = playerIndex === 0 ? 192 : 160;\n          let baseSx = 0;\n          switch (state) {\n            case \"DOWN\":\n              baseSx = 0;\n              break;\n            case \"LEFT\":\n              baseSx = 3 * SPRITE_WIDTH;\n              break;\n            case \"RIGHT\":\n              baseSx = 6 * SPRITE_WIDTH;\n              break;\n            case \"UP\":\n              baseSx = 9 * SPRITE_WIDTH;\n              break;\n            case \"DEAD\":\n              baseSx = 12 * SPRITE_WIDTH;\n              break;\n            case \"IDLE\":\n            default:\n              baseSx = 0;\n              break;\n          }\n\n          const sx =\n            state === \"IDLE\" ? baseSx : baseSx + animationFrame * SPRITE_WIDTH;\n\n          // Draw Player Sprite\n          ctx.drawImage(\n            textureAtlas,\n            sx,\n            sy,\n            SPRITE_WIDTH,\n            SPRITE_WIDTH, // Assuming square sprites\n            player.pos.x * tileWidth,\n            player.pos.y * tileHeight,\n            tileWidth,\n            tileHeight,\n          );\n\n          const lives = player.health !== undefined ? player.health : MAX_LIVES;\n          if (lives > 0) {\n            const heartRenderWidth = tileWidth / 2.5;\n            const heartRenderHeight = tileHeight / 2.5;\n            const totalHeartsWidth = MAX_LIVES * heartRenderWidth;\n            const startX =\n              player.pos.x * tileWidth + tileWidth / 2 - totalHeartsWidth / 2;\n            const startY = player.pos.y * tileHeight - heartRenderHeight * 1.1; // Place slightly above the tile\n\n            const playerName = playerIDToName[player.authToken];\n            if (playerName) {\n              ctx.fillStyle = \"#FFF\";\n              ctx.font = \"bold 10px monospace\";\n              ctx.textAlign = \"center\";\n              ctx.fillText(\n                playerName,\n                player.pos.x * tileWidth + tileWidth / 2,\n                startY + 35,\n              );\n            }\n\n            for (let i = 0; i < MAX_LIVES; i++) {\n              const isFull = i < lives;\n              const heartSx = isFull ? FULL_HEART_SX : EMPTY_HEART_SX;\n\n              ctx.drawImage(\n                textureAtlas,\n                heartSx,\n                HEARTS_SY,\n                HEART_SPRITE_WIDTH,\n                HEART_SPRITE_HEIGHT,\n                startX + i * heartRenderWidth,\n                startY,\n                heartRenderWidth,\n                heartRenderHeight,\n              );\n            }\n          }\n        });\n      }\n\n      function getPlayerById(id) {\n        // Find the player with the given id in the first tick\n        const firstTick = history.ticks[0];\n        return firstTick.players.find((p) => p.id === id);\n      }\n\n      let animationFrame = 0;\n      const FRAME_COUNT = 3;\n      const SPRITE_WIDTH = 32;\n\n      function drawBombs(bombs) {\n        const sx = animationFrame * SPRITE_WIDTH;\n        const sy = 64;\n\n\n\n        bombs.forEach((bomb) => {\n          ctx.drawImage(\n            textureAtlas,\n            sx,\n            sy,\n            SPRITE_WIDTH,\n            SPRITE_WIDTH,\n            bomb.pos.x * tileWidth,\n            bomb.pos.y * tileHeight,\n            tileWidth,\n            tileHeight,\n          );\n          ctx.fillStyle = bomb.fuse < 4 ? \"#F10\" : \"#FFF\";\n          if (bomb.fuse < 7) {\n            ctx.fillStyle = bomb.fuse < 4 ? \"#F10\" : \"#F80\";\n          }\n          ctx.font = \"bold 8px monospace\";\n          ctx.textAlign = \"center\";\n          ctx.fillText(\n            bomb.fuse,\n            bomb.pos.x * tileWidth - 2 + tileWidth / 2,\n            bomb.pos.y * tileHeight + tileHeight / 2 + 13,\n          );\n        });\n      }\n\n      function drawExplosions(explosions) {\n        if (!explosions || explosions.length === 0) {\n          return;\n        }\n        const explosionSet = new Set(\n          explosions.map((exp) => `${exp.x},${exp.y}`),\n        );\n\n        explosions.forEach((exp) => {\n          const hasUp = explosionSet.has(`${exp.x},${exp.y - 1}`);\n          const hasDown = explosionSet.has(`${exp.x},${exp.y + 1}`);\n          const hasLeft = explosionSet.has(`${exp.x - 1},${exp.y}`);\n          const hasRight = explosionSet.has(`${exp.x + 1},${exp.y}`);\n\n          // Bitmask: 8 (U), 4 (D), 2 (L), 1 (R)\n          const neighbors =\n            (hasUp << 3) | (hasDown << 2) | (hasLeft << 1) | hasRight;\n\n          let baseSx = 0;\n          let sy = 0;\n\n          switch (neighbors) {\n            // End-caps\n            case 1: // Right only\n              baseSx = 0;\n              sy = 13 * 32;\n              break;\n            case 2: // Left only\n              baseSx = 0;\n              sy = 11 * 32;\n              break;\n            case 4: // Down only\n              baseSx = 0;\n              sy = 10 * 32;\n              break;\n            case 8: // Up only\n              baseSx = 0;\n              sy = 12 * 32;\n              break;\n\n            // Straight pieces\n            case 3: // Left-Right\n              baseSx = 0;\n              sy = 9 * 32;\n              break;\n            case 12: // Up-Down\n              baseSx = 0;\n              sy = 8 * 32;\n              break; // Fallback to cross\n\n            // Corners\n            case 6: // Down-Left\n              baseSx = 96;\n              sy = 7 * 32;\n              break;\n            case 5: // Down-Right\n              baseSx = 96;\n              sy = 10 * 32;\n              break;\n            case 10: // Up-Left\n              baseSx = 96;\n              sy = 8 * 32;\n              break;\n            case 9: // Up-Right\n              baseSx = 96;\n              sy = 9 * 32;\n              break;\n\n            // T-Junctions\n            case 7: // Down-Left-Right\n              baseSx = 96;\n              sy = 14 * 32;\n              break;\n            case 11: // Up-Left-Right\n              baseSx = 96;\n              sy = 12 * 32;\n              break;\n            case 13: // Up-Down-Right\n              baseSx = 96;\n              sy = 13 * 32;\n              break;\n            case 14: // Up-Down-Left\n              baseSx = 96;\n              sy = 11 * 32;\n              break;\n\n            // Cross and default\n            case 15: // All directions\n            default:\n              baseSx = 0;\n              sy = 7 * 32;\n              break;\n          }\n\n          const sx = baseSx + animationFrame * SPRITE_WIDTH;\n          ctx.drawImage(\n            textureAtlas,\n            sx,\n            sy,\n            SPRITE_WIDTH,\n            SPRITE_WIDTH,\n            exp.x * tileWidth,\n            exp.y * tileHeight,\n            tileWidth,\n            tileHeight,\n          );\n        });\n      }\n\n      function drawDestroyedBoxes(boxes, field) {\n        boxes.forEach((box) => {\n          field[box.y * fieldWidth + box.x] = \" \";\n        });\n      }\n\n      function renderTick(tickIndex, isNewTick = true) {\n        if (isNewTick) {\n          currentTick = tickIndex;\n        }\n        ctx.clearRect(0, 0, canvas.width, canvas.height);\n        const tick = history.ticks[tickIndex];\n        if (!tick) return;\n\n        // Rebuild field state up to the current tick\n        let field = [...history.initial_field.field];\n        for (let i = 0; i <= tickIndex; i++) {\n          const pastTick = history.ticks[i];\n          if (pastTick.destroyed_boxes) {\n            drawDestroyedBoxes(pastTick.destroyed_boxes, field);\n          }\n        }\n\n        drawField(field);\n        drawPlayers(tick.players);\n        drawBombs(tick.bombs);\n        drawExplosions(tick.explosions);\n\n        tickCounter.textContent = `Tick: ${tickIndex} / ${totalTicks}`;\n        updateMoveHighlight(tickIndex, isNewTick);\n      }\n\n      prevBtn.addEventListener(\"click\", () => {\n        if (currentTick > 0) {\n          renderTick(currentTick - 1);\n        }\n      });\n\n      nextBtn.addEventListener(\"click\", () => {\n        if (currentTick < totalTicks) {\n          renderTick(currentTick + 1);\n        }\n      });\n\n      let lastFrameTime = 0;\n      const ANIMATION_INTERVAL = 200; // ms per frame\n\n      function animationLoop(currentTime) {\n        const deltaTime = currentTime - lastFrameTime;\n\n        if (deltaTime > ANIMATION_INTERVAL) {\n          lastFrameTime = currentTime;\n          animationFrame = (animationFrame + 1) % FRAME_COUNT;\n          // Re-render the current tick without changing it\n          renderTick(currentTick, false);\n        }\n\n        requestAnimationFrame(animationLoop);\n      }\n\n      // Initial render\n      textureAtlas.onload = () => {\n        populateMoveList();\n        renderTick(0);\n        requestAnimationFrame(animationLoop);\n      };\n    </script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "\";\n\n        history.ticks.forEach((tick, index) => {\n          const moveEntry = document.createElement(\"tr\");\n          moveEntry.classList.add(\"hover\"); // DaisyUI class for hover effect\n          moveEntry.dataset.tick = index;\n\n          const prevTick = index > 0 ? history.ticks[index - 1] : null;\n          const moves = {};\n\n          if (tick.players) {\n            tick.players.forEach((player) => {\n              const prevPlayer = prevTick ? prevTick.players.find(p => p.id === player.id) : null;\n              let move = \"\";\n              const eliminated = tick.events\n                ? tick.events.some(e => e.type === \"player_eliminated\" && e.playerId === player.id)\n                : prevPlayer && prevPlayer.health > 0 && player.health === 0;\n              // Check for death\n              if (eliminated) {\n                  move = '💀';\n              }\n              // If not dead, show the move\n              else if (player.move) {\n                  move = player.bomb ? `💣 + ${player.move}` : player.move;\n              }\n              const latency = tick.latencies_ms && tick.latencies_ms[player.authToken || player.id];\n              if (latency !== undefined && move !== '💀') {\n                  move += ` (${latency}ms)`;\n              }\n              moves[player.authToken] = move;\n            });\n          }\n\n          const player1Move = moves[bot1Id] || \"\";\n          const player2Move = moves[bot2Id] || \"\";\n\n          const numBombs = tick.bombs ? tick.bombs.length : 0;\n          const numExplosions = tick.explosions ? tick.explosions.length : 0;\n\n          let livesLost = 0;\n          if (tick.events) {\n            // Newer histories record what happened in each tick\n            livesLost = tick.events.filter(e => e.type === \"player_damaged\").length;\n          } else if (index > 0) {\n            const prevTick = history.ticks[index - 1];\n            if (tick.players && prevTick.players) {\n                tick.players.forEach(currentPlayer => {\n                    const prevPlayer = prevTick.players.find(p => p.id === currentPlayer.id);\n                    if (prevPlayer && currentPlayer.health < prevPlayer.health) {\n                        livesLost += (prevPlayer.health - currentPlayer.health);\n                    }\n                });\n            }\n          }\n\n          let eventsStr = \"\";\n          if (livesLost > 0) {\n              eventsStr += `${livesLost}💔 `;\n          }\n          if (numBombs > 0) {\n              eventsStr += `${numBombs}💣 `;\n          }\n          if (numExplosions > 0) {\n              eventsStr += `${numExplosions}💥`;\n          }\n\n          moveEntry.innerHTML = `\n            <th>${index}</th>\n            <td><span class=\"font-mono\">${player1Move}</span></td>\n            <td><span class=\"font-mono\">${player2Move}</span></td>\n            <td>${eventsStr}</td>\n          `;\n\n          moveEntry.addEventListener(\"click\", () => {\n            renderTick(index);\n          });\n          moveListTbody.appendChild(moveEntry);\n        });\n      }\n\n      function isMoveEntryVisible(moveEntry, container) {\n        if (!moveEntry || !container) return false;\n        const entryRect = moveEntry.getBoundingClientRect();\n        const containerRect = container.getBoundingClientRect();\n        return (\n          entryRect.top >= containerRect.top &&\n          entryRect.bottom <= containerRect.bottom\n        );\n      }\n\n      function updateMoveHighlight(tickIndex, shouldScroll = false) {\n        const moveEntries = moveListTbody.children;\n        const moveListContainer = moveListTbody.closest(\".overflow-y-auto\");\n        for (let i = 0; i < moveEntries.length; i++) {\n          if (parseInt(moveEntries[i].dataset.tick) === tickIndex) {\n            moveEntries[i].classList.add(\"active\", \"outline\", \"outline-2\", \"outline-primary\");\n            if (\n              shouldScroll &&\n              moveListContainer &&\n              !isMoveEntryVisible(moveEntries[i], moveListContainer)\n            ) {\n              moveEntries[i].scrollIntoView({ block: \"center\", behavior: \"smooth\" });\n            }\n          } else {\n            moveEntries[i].classList.remove(\"active\", \"outline\", \"outline-2\", \"outline-primary\");\n          }\n        }\n      }\n\n      const tileColors = {\n        AIR: \"lightgray\", // Empty\n        WALL: \"gray\", // Wall\n        BOX: \"sandybrown\", // Box\n      };\n\n      function drawField(field) {\n        for (let y = 0; y < fieldHeight; y++) {\n          for (let x = 0; x < fieldWidth; x++) {\n            const tile = field[y * fieldWidth + x];\n            const destX = x * tileWidth;\n            const destY = y * tileHeight;\n            const baseSpriteWidth = 32;\n            const baseSpriteHeight = 32;\n\n            // Always draw floor first\n            ctx.drawImage(\n              textureAtlas,\n              64, // floor sx\n              0, // floor sy\n              baseSpriteWidth,\n              baseSpriteHeight,\n              destX,\n              destY,\n              tileWidth,\n              tileHeight,\n            );\n\n            if (tile === \"WALL\") {\n              const hasWallUp =\n                y > 0 && field[(y - 1) * fieldWidth + x] === \"WALL\";\n              const hasWallDown =\n                y < fieldHeight - 1 &&\n                field[(y + 1) * fieldWidth + x] === \"WALL\";\n              const hasWallLeft =\n                x > 0 && field[y * fieldWidth + (x - 1)] === \"WALL\";\n              const hasWallRight =\n                x < fieldWidth - 1 &&\n                field[y * fieldWidth + (x + 1)] === \"WALL\";\n\n              let sx = 0;\n              const sy = 32; // Wall sprites are in the second row\n\n              // The logic to select the correct wall sprite based on neighbors.\n              // Bitmask: 8 (Up), 4 (Down), 2 (Left), 1 (Right)\n              const neighbors =\n                (hasWallUp << 3) |\n                (hasWallDown << 2) |\n                (hasWallLeft << 1) |\n                hasWallRight;\n\n              switch (neighbors) {\n                case 0: // No neighbors: solitary wall\n                  sx = 192;\n                  break;\n                case 1: // Right only\n                case 2: // Left only\n                case 3: // Left and Right: horizontal wall\n                  sx = 0;\n                  break;\n                case 4: // Down only\n                case 8: // Up only\n                case 12: // Up and Down: vertical wall\n                  sx = 32;\n                  break;\n                case 5: // Down and Right: corner ╔\n                  sx = 64;\n                  break;\n                case 6: // Down and Left: corner ╗\n                  sx = 96;\n                  break;\n                case 9: // Up and Right: corner ╚\n                  sx = 128;\n                  break;\n                case 10: // Up and Left: corner ╝\n                  sx = 160;\n                  break;\n                default:\n                  // T-junctions and Crosses\n                  if (neighbors & 3) {\n                    // Has Left or Right, prioritize horizontal\n                    sx = 0;\n                  } else {\n                    // Must be a T-junction pointing left/right, use vertical\n                    sx = 32;\n                  }\n                  break;\n              }\n\n              ctx.drawImage(\n                textureAtlas,\n                sx,\n                sy,\n                baseSpriteWidth,\n                baseSpriteHeight,\n                destX,\n                destY,\n                tileWidth,\n                tileHeight,\n              );\n            } else if (tile === \"BOX\") {\n              ctx.drawImage(\n                textureAtlas,\n                32, // sx\n                0, // sy\n                baseSpriteWidth,\n                baseSpriteHeight,\n                destX,\n                destY,\n                tileWidth,\n                tileHeight,\n              );\n            }\n            // For AIR tiles, the floor is already drawn, so do nothing else.\n          }\n        }\n      }\n\n      function drawPlayers(players) {\n        const MAX_LIVES =\n          (history.config && history.config.initialHealth) || 3;\n        const HEART_SPRITE_WIDTH = 16;\n        const HEART_SPRITE_HEIGHT = 16;\n        const FULL_HEART_SX = 96;\n        const EMPTY_HEART_SX = 112;\n        const HEARTS_SY = 0;\n        const SPRITE_WIDTH = 32;\n\n        players.forEach((player) => {\n          // Determine player state\n          let state = \"IDLE\";\n          if (player.health === 0) {\n            state = \"DEAD\";\n          } else if (currentTick > 0) {\n            const prevTick = history.ticks[currentTick - 1];\n            const prevPlayer = prevTick.players.find((p) => p.id === player.id);\n            if (prevPlayer) {\n              if (player.pos.x > prevPlayer.pos.x) state = \"RIGHT\";\n              else if (player.pos.x < prevPlayer.pos.x) state = \"LEFT\";\n              else if (player.pos.y > prevPlayer.pos.y) state = \"DOWN\";\n              else if (player.pos.y < prevPlayer.pos.y) state = \"UP\";\n            }\n          }\n\n          // Determine sprite coordinates\n          const playerIndex = playerIndexMap.get(player.id) || 0;\n          const sy = playerIndex === 0 ? 192 : 160;\n          let baseSx = 0;\n          switch (state) {\n            case \"DOWN\":\n              baseSx = 0;\n              break;\n            case \"LEFT\":\n              baseSx = 3 * SPRITE_WIDTH;\n              break;\n            case \"RIGHT\":\n              baseSx = 6 * SPRITE_WIDTH;\n              break;\n            case \"UP\":\n              baseSx = 9 * SPRITE_WIDTH;\n              break;\n            case \"DEAD\":\n              baseSx = 12 * SPRITE_WIDTH;\n              break;\n            case \"IDLE\":\n            default:\n              baseSx = 0;\n              break;\n          }\n\n          const sx =\n            state === \"IDLE\" ? baseSx : baseSx + animationFrame * SPRITE_WIDTH;\n\n          // Draw Player Sprite\n          ctx.drawImage(\n            textureAtlas,\n            sx,\n            sy,\n            SPRITE_WIDTH,\n            SPRITE_WIDTH, // Assuming square sprites\n            player.pos.x * tileWidth,\n            player.pos.y * tileHeight,\n            tileWidth,\n            tileHeight,\n          );\n\n          const lives = player.health !== undefined ? player.health : MAX_LIVES;\n          if (lives > 0) {\n            const heartRenderWidth = tileWidth / 2.5;\n            const heartRenderHeight = tileHeight / 2.5;\n            const totalHeartsWidth = MAX_LIVES * heartRenderWidth;\n            const startX =\n              player.pos.x * tileWidth + tileWidth / 2 - totalHeartsWidth / 2;\n            const startY = player.pos.y * tileHeight - heartRenderHeight * 1.1; // Place slightly above the tile\n\n            const playerName = playerIDToName[player.authToken];\n            if (playerName) {\n              ctx.fillStyle = \"#FFF\";\n              ctx.font = \"bold 10px monospace\";\n              ctx.textAlign = \"center\";\n              ctx.fillText(\n                playerName,\n                player.pos.x * tileWidth + tileWidth / 2,\n                startY + 35,\n              );\n            }\n\n            for (let i = 0; i < MAX_LIVES; i++) {\n              const isFull = i < lives;\n              const heartSx = isFull ? FULL_HEART_SX : EMPTY_HEART_SX;\n\n              ctx.drawImage(\n                textureAtlas,\n                heartSx,\n                HEARTS_SY,\n                HEART_SPRITE_WIDTH,\n                HEART_SPRITE_HEIGHT,\n                startX + i * heartRenderWidth,\n                startY,\n                heartRenderWidth,\n                heartRenderHeight,\n              );\n            }\n          }\n        });\n      }\n\n      function getPlayerById(id) {\n        // Find the player with the given id in the first tick\n        const firstTick = history.ticks[0];\n        return firstTick.players.find((p) => p.id === id);\n      }\n\n      let animationFrame = 0;\n      const FRAME_COUNT = 3;\n      const SPRITE_WIDTH = 32;\n\n      function drawBombs(bombs) {\n        const sx = animationFrame * SPRITE_WIDTH;\n        const sy = 64;\n\n\n\n        bombs.forEach((bomb) => {\n          ctx.drawImage(\n            textureAtlas,\n            sx,\n            sy,\n            SPRITE_WIDTH,\n            SPRITE_WIDTH,\n            bomb.pos.x * tileWidth,\n            bomb.pos.y * tileHeight,\n            tileWidth,\n            tileHeight,\n          );\n          ctx.fillStyle = bomb.fuse < 4 ? \"#F10\" : \"#FFF\";\n          if (bomb.fuse < 7) {\n            ctx.fillStyle = bomb.fuse < 4 ? \"#F10\" : \"#F80\";\n          }\n          ctx.font = \"bold 8px monospace\";\n          ctx.textAlign = \"center\";\n          ctx.fillText(\n            bomb.fuse,\n            bomb.pos.x * tileWidth - 2 + tileWidth / 2,\n            bomb.pos.y * tileHeight + tileHeight / 2 + 13,\n          );\n        });\n      }\n\n      function drawExplosions(explosions) {\n        if (!explosions || explosions.length === 0) {\n          return;\n        }\n        const explosionSet = new Set(\n          explosions.map((exp) => `${exp.x},${exp.y}`),\n        );\n\n        explosions.forEach((exp) => {\n          const hasUp = explosionSet.has(`${exp.x},${exp.y - 1}`);\n          const hasDown = explosionSet.has(`${exp.x},${exp.y + 1}`);\n          const hasLeft = explosionSet.has(`${exp.x - 1},${exp.y}`);\n          const hasRight = explosionSet.has(`${exp.x + 1},${exp.y}`);\n\n          // Bitmask: 8 (U), 4 (D), 2 (L), 1 (R)\n          const neighbors =\n            (hasUp << 3) | (hasDown << 2) | (hasLeft << 1) | hasRight;\n\n          let baseSx = 0;\n          let sy = 0;\n\n          switch (neighbors) {\n            // End-caps\n            case 1: // Right only\n              baseSx = 0;\n              sy = 13 * 32;\n              break;\n            case 2: // Left only\n              baseSx = 0;\n              sy = 11 * 32;\n              break;\n            case 4: // Down only\n              baseSx = 0;\n              sy = 10 * 32;\n              break;\n            case 8: // Up only\n              baseSx = 0;\n              sy = 12 * 32;\n              break;\n\n            // Straight pieces\n            case 3: // Left-Right\n              baseSx = 0;\n              sy = 9 * 32;\n              break;\n            case 12: // Up-Down\n              baseSx = 0;\n              sy = 8 * 32;\n              break; // Fallback to cross\n\n            // Corners\n            case 6: // Down-Left\n              baseSx = 96;\n              sy = 7 * 32;\n              break;\n            case 5: // Down-Right\n              baseSx = 96;\n              sy = 10 * 32;\n              break;\n            case 10: // Up-Left\n              baseSx = 96;\n              sy = 8 * 32;\n              break;\n            case 9: // Up-Right\n              baseSx = 96;\n              sy = 9 * 32;\n              break;\n\n            // T-Junctions\n            case 7: // Down-Left-Right\n              baseSx = 96;\n              sy = 14 * 32;\n              break;\n            case 11: // Up-Left-Right\n              baseSx = 96;\n              sy = 12 * 32;\n              break;\n            case 13: // Up-Down-Right\n              baseSx = 96;\n              sy = 13 * 32;\n              break;\n            case 14: // Up-Down-Left\n              baseSx = 96;\n              sy = 11 * 32;\n              break;\n\n            // Cross and default\n            case 15: // All directions\n            default:\n              baseSx = 0;\n              sy = 7 * 32;\n              break;\n          }\n\n          const sx = baseSx + animationFrame * SPRITE_WIDTH;\n          ctx.drawImage(\n            textureAtlas,\n            sx,\n            sy,\n            SPRITE_WIDTH,\n            SPRITE_WIDTH,\n            exp.x * tileWidth,\n            exp.y * tileHeight,\n            tileWidth,\n            tileHeight,\n          );\n        });\n      }\n\n      const powerUpIcons = {\n        EXTRA_BOMB: \"➕\",\n        BLAST_RADIUS: \"🔥\",\n        BOMB_PASS: \"👟\",\n        BOMB_KICK: \"🦶\",\n        SHIELD: \"🛡️\",\n      };\n\n      function drawPowerUps(powerUps) {\n        if (!powerUps || powerUps.length === 0) {\n          return;\n        }\n        ctx.font = `${Math.floor(tileHeight * 0.6)}px sans-serif`;\n        ctx.textAlign = \"center\";\n        ctx.textBaseline = \"middle\";\n        powerUps.forEach((powerUp) => {\n          const icon = powerUpIcons[powerUp.type];\n          if (!icon) return;\n          ctx.fillText(\n            icon,\n            powerUp.pos.x * tileWidth + tileWidth / 2,\n            powerUp.pos.y * tileHeight + tileHeight / 2,\n          );\n        });\n        ctx.textBaseline = \"alphabetic\";\n      }\n\n      function drawDestroyedBoxes(boxes, field) {\n        boxes.forEach((box) => {\n          field[box.y * fieldWidth + box.x] = \" \";\n        });\n      }\n\n      // Walls closing in during the sudden death\n      function drawClosedWalls(walls, field) {\n        walls.forEach((wall) => {\n          field[wall.y * fieldWidth + wall.x] = \"WALL\";\n        });\n      }\n\n      const viewSelect = document.getElementById(\"view-select\");\n      let viewAuthToken = \"\";\n\n      // Fog of war games record what each player saw, so the replay can show their view\n      function populateViewSelect() {\n        const firstTick = history.ticks[0];\n        if (!firstTick || !firstTick.visible) return;\n        firstTick.players.forEach((player) => {\n          const option = document.createElement(\"option\");\n          option.value = player.authToken || player.id;\n          option.textContent = `View of ${playerIDToName[player.authToken] || player.id}`;\n          viewSelect.appendChild(option);\n        });\n        viewSelect.classList.remove(\"hidden\");\n        viewSelect.addEventListener(\"change\", () => {\n          viewAuthToken = viewSelect.value;\n          renderTick(currentTick, false);\n        });\n      }\n\n      function drawFog(visible) {\n        if (!viewAuthToken || !visible || !visible[viewAuthToken]) return;\n        const visibleSet = new Set(visible[viewAuthToken]);\n        ctx.fillStyle = \"rgba(0, 0, 0, 0.7)\";\n        for (let i = 0; i < fieldWidth * fieldHeight; i++) {\n          if (!visibleSet.has(i)) {\n            ctx.fillRect(\n              (i % fieldWidth) * tileWidth,\n              Math.floor(i / fieldWidth) * tileHeight,\n              tileWidth,\n              tileHeight,\n            );\n          }\n        }\n      }\n\n      // Shows which bot was too slow to answer the states in time\n      function populateInputStats() {\n        if (!history.input_stats) return;\n        const inputStats = document.getElementById(\"input-stats\");\n        Object.entries(history.input_stats).forEach(([authToken, stats]) => {\n          const row = document.createElement(\"div\");\n          row.textContent =\n            `${playerIDToName[authToken] || \"Unknown\"}: ` +\n            `avg ${stats.avgLatencyMs}ms, max ${stats.maxLatencyMs}ms, ` +\n            `${stats.missedTicks} missed ticks, ${stats.staleInputs} late inputs, ` +\n            `${stats.futureInputs || 0} inputs for future ticks`;\n          inputStats.appendChild(row);\n        });\n      }\n\n      function renderTick(tickIndex, isNewTick = true) {\n        if (isNewTick) {\n          currentTick = tickIndex;\n        }\n        ctx.clearRect(0, 0, canvas.width, canvas.height);\n        const tick = history.ticks[tickIndex];\n        if (!tick) return;\n\n        // Rebuild field state up to the current tick\n        let field = [...history.initial_field.field];\n        for (let i = 0; i <= tickIndex; i++) {\n          const pastTick = history.ticks[i];\n          if (pastTick.destroyed_boxes) {\n            drawDestroyedBoxes(pastTick.destroyed_boxes, field);\n          }\n          if (pastTick.closed_walls) {\n            drawClosedWalls(pastTick.closed_walls, field);\n          }\n        }\n\n        drawField(field);\n        drawPowerUps(tick.power_ups);\n        drawPlayers(tick.players);\n        drawBombs(tick.bombs);\n        drawExplosions(tick.explosions);\n        drawFog(tick.visible);\n\n        tickCounter.textContent = `Tick: ${tickIndex} / ${totalTicks}`;\n        updateMoveHighlight(tickIndex, isNewTick);\n      }\n\n      prevBtn.addEventListener(\"click\", () => {\n        if (currentTick > 0) {\n          renderTick(currentTick - 1);\n        }\n      });\n\n      nextBtn.addEventListener(\"click\", () => {\n        if (currentTick < totalTicks) {\n          renderTick(currentTick + 1);\n        }\n      });\n\n      let lastFrameTime = 0;\n      const ANIMATION_INTERVAL = 200; // ms per frame\n\n      function animationLoop(currentTime) {\n        const deltaTime = currentTime - lastFrameTime;\n\n        if (deltaTime > ANIMATION_INTERVAL) {\n          lastFrameTime = currentTime;\n          animationFrame = (animationFrame + 1) % FRAME_COUNT;\n          // Re-render the current tick without changing it\n          renderTick(currentTick, false);\n        }\n\n        requestAnimationFrame(animationLoop);\n      }\n\n      // Initial render\n      textureAtlas.onload = () => {\n        populateMoveList();\n        populateViewSelect();\n        populateInputStats();\n        renderTick(0);\n        requestAnimationFrame(animationLoop);\n      };\n    </script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}